@@ -0,0 +1,100 @@
+package instance
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// SetNamedMark records the selected entry under the name, replacing any mark with the same name
+func (i *Instance) SetNamedMark(name string) error {
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return errors.New(fmt.Sprintf("mark: '%s' is not a valid mark name; names can't be empty or contain whitespace.", name))
+	}
+	index := i.tree.CurrentIndex()
+	if !i.tree.IsInRange(index) {
+		return errors.New("mark: nothing is selected.")
+	}
+	i.namedMarks[name] = i.tree.Array()[index].Path
+	return i.saveNamedMarks()
+}
+
+// GotoNamedMark selects the entry recorded under the name, which is found by path so marks survive rescans and view changes
+func (i *Instance) GotoNamedMark(name string) error {
+	path, ok := i.namedMarks[name]
+	if !ok {
+		return errors.New(fmt.Sprintf("goto_mark: there is no mark named '%s'.", name))
+	}
+	index, ok := i.tree.IndexOfPath(path)
+	if !ok {
+		return errors.New(fmt.Sprintf("goto_mark: mark '%s' is for '%s', which is no longer in the tree.", name, path))
+	}
+	i.tree.Select(index)
+	i.tree.Draw()
+	return nil
+}
+
+func (i *Instance) DeleteNamedMark(name string) error {
+	if _, ok := i.namedMarks[name]; !ok {
+		return errors.New(fmt.Sprintf("delete_mark: there is no mark named '%s'.", name))
+	}
+	delete(i.namedMarks, name)
+	return i.saveNamedMarks()
+}
+
+// namedMarkNames returns the names of every named mark in alphabetical order
+func (i *Instance) namedMarkNames() []string {
+	names := []string{}
+	for name := range i.namedMarks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadNamedMarks reads marks from a file and remembers it so marks are saved back to it whenever they change
+// each line of the file is <name> <path>, separated by a tab
+func (i *Instance) LoadNamedMarks(filename string) error {
+	i.marksFile = filename
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		// the file will be created once a mark is set
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(fields) != 2 || fields[0] == "" {
+			return errors.New(fmt.Sprintf("marks: line %d of '%s' is malformed.", line, filename))
+		}
+		i.namedMarks[fields[0]] = fields[1]
+	}
+	return scanner.Err()
+}
+
+func (i *Instance) saveNamedMarks() error {
+	if i.marksFile == "" {
+		return nil
+	}
+	f, err := os.Create(i.marksFile)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, name := range i.namedMarkNames() {
+		fmt.Fprintf(w, "%s\t%s\n", name, i.namedMarks[name])
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
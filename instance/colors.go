@@ -0,0 +1,28 @@
+package instance
+
+import (
+	"github.com/StructsNotClasses/mim/instance/theme"
+)
+
+// setThemeColor changes the style of one element from the arguments of :theme_color
+func (i *Instance) setThemeColor(args []string) error {
+	element, err := theme.ParseElement(args[0])
+	if err != nil {
+		return err
+	}
+	fg, err := theme.ParseColor(args[1])
+	if err != nil {
+		return err
+	}
+	bg, err := theme.ParseColor(args[2])
+	if err != nil {
+		return err
+	}
+	s := theme.Style{Foreground: fg, Background: bg}
+	if len(args) > 3 {
+		if s.Attributes, err = theme.ParseAttributes(args[3]); err != nil {
+			return err
+		}
+	}
+	return i.palette.SetStyle(element, s)
+}
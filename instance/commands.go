@@ -1,6 +1,10 @@
 package instance
 
 import (
+	"github.com/StructsNotClasses/mim/instance/dirtree"
+	"github.com/StructsNotClasses/mim/instance/layout"
+	"github.com/StructsNotClasses/mim/instance/theme"
+	"github.com/StructsNotClasses/mim/musicarray"
 	"github.com/StructsNotClasses/mim/script"
 
 	gnc "github.com/rthornton128/goncurses"
@@ -9,13 +13,29 @@ import (
 	"io/ioutil"
 	"log"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
 func (instance *Instance) runCommand(cmd string) bool {
+	if line := strings.TrimSuffix(cmd, "\n"); line == ":eval" || strings.HasPrefix(line, ":eval ") {
+		// runs tengo and prints the value if it's an expression, without needing :begin and :end
+		// variables it defines are kept for later :eval commands and lines entered in the repl
+		// the tengo is taken as written before the command is split, since its quotes don't follow the rules for arguments
+		// eg :eval songCount() / 2
+		// :eval <tengo>
+		source := strings.TrimSpace(strings.TrimPrefix(cmd, ":eval"))
+		if source == "" {
+			instance.terminal.ErrorPrintln("eval: expected tengo to evaluate.")
+		} else {
+			instance.evalAndPrint(source)
+		}
+		return false
+	}
+
 	args, err := splitCommand(cmd)
 	if err != nil {
-		instance.terminal.InfoPrintln(err)
+		instance.terminal.ErrorPrintln(err)
 		return false
 	}
 
@@ -47,17 +67,18 @@ func (instance *Instance) runCommand(cmd string) bool {
 		//     if a character is being bound, the multiline script will be bound to the character, state will reflect that nothing is being bound, and the script will not be executed
 		//     until the character is pressed
 		//     the same goes for if the 'on_no_playback' script is being set. these two can occur simultaneously.
+		//     if the script is being attached to an event with :on, it is only attached to the event
 		//     if nothing was being set, the script will be executed once
 		// this command triggers compilation of the script and always clears the buffer
 		// :end <name>?
 		if !instance.terminal.ScriptBeingWritten() {
-			instance.terminal.InfoPrintln("end: called outside of script-writing environment")
+			instance.terminal.ErrorPrintln("end: called outside of script-writing environment")
 			return false
 		}
 		instance.terminal.EndScript()
 		compiled, err := instance.compileScript(instance.terminal.WrittenScript())
 		if err != nil {
-			instance.terminal.InfoPrintln(err)
+			instance.terminal.ErrorPrintln(err)
 		} else {
 			if len(args) > 1 {
 				instance.manageScript(script.New(args[1], instance.terminal.WrittenScript(), compiled))
@@ -73,7 +94,7 @@ func (instance *Instance) runCommand(cmd string) bool {
 			instance.terminal.ClearWrittenScript()
 			instance.terminal.EndScript()
 		} else {
-			instance.terminal.InfoPrintln("cancel: cannot call outside of script-writing environment")
+			instance.terminal.ErrorPrintln("cancel: cannot call outside of script-writing environment")
 		}
 	case "on_no_playback":
 		// changes state such that the next script processed will be run whenever nothing is currently playing
@@ -84,6 +105,52 @@ func (instance *Instance) runCommand(cmd string) bool {
 		if instance.terminal.RequireArgCount(args, 1) {
 			instance.terminal.BindNextScriptToNoPlayback()
 		}
+	case "on":
+		// changes state such that the next script processed is attached to the event instead of being run, like on_no_playback
+		// an event can have any number of scripts attached, told apart by name; attaching one with the name of an attached script replaces it and an empty one removes it
+		// the events are song_start, song_end, selection_changed, startup, shutdown, search_changed, library_rescanned and idle, which runs once after that many seconds without input
+		// while it runs the script can read the map 'hookEvent', which has the event's name, the index and path of the entry it's about (-1 and "" when there isn't one) and the reason it happened
+		// search_changed also has the new search as 'search' and idle has how long there's been no input as 'seconds'
+		// a hook doesn't run for events its own event's hooks cause, but does for others, so a song_end hook playing a song runs the song_start hooks
+		// eg :on song_end
+		//    :begin
+		//    infoPrintln("finished " + hookEvent.path)
+		//    :end announce
+		// :on <event> <seconds>?
+		if instance.terminal.RequireArgCountGTE(args, 2) {
+			event, idle, err := parseHook(args)
+			if err != nil {
+				instance.terminal.ErrorPrintln(err)
+			} else {
+				instance.terminal.SetNextHook(event, idle)
+			}
+		}
+	case "remove_hook":
+		// removes the script with the name from the event
+		// :remove_hook <event> <name>
+		if instance.terminal.RequireArgCount(args, 3) && !instance.RemoveHook(args[1], args[2]) {
+			instance.terminal.ErrorPrintf("remove_hook: no script named '%s' is attached to '%s'.\n", args[2], args[1])
+		}
+	case "clear_hooks":
+		// removes every script from the event, or from every event if none is given
+		// :clear_hooks <event>?
+		if len(args) == 1 {
+			instance.ClearHooks("")
+		} else if instance.terminal.RequireArgCount(args, 2) {
+			instance.ClearHooks(args[1])
+		}
+	case "hooks":
+		// lists the scripts attached to each event
+		// :hooks
+		if instance.terminal.RequireArgCount(args, 1) {
+			descriptions := instance.hookDescriptions()
+			if len(descriptions) == 0 {
+				instance.terminal.InfoPrintln("hooks: no scripts are attached to events.")
+			}
+			for _, d := range descriptions {
+				instance.terminal.InfoPrintln(d)
+			}
+		}
 	case "print_buffer":
 		// print the current buffer contents to the info window
 		// mostly for debugging or checking if empty or something
@@ -100,15 +167,15 @@ func (instance *Instance) runCommand(cmd string) bool {
 		// this can't be called while writing a tengo script because it would unset the binding state for the enclosing script
 		// :load_script <filename>
 		if instance.terminal.ScriptBeingWritten() {
-			instance.terminal.InfoPrintln("load_script: cannot call while writing a script.")
+			instance.terminal.ErrorPrintln("load_script: cannot call while writing a script.")
 		} else if instance.terminal.RequireArgCount(args, 2) {
 			bytes, err := ioutil.ReadFile(args[1])
 			if err != nil {
-				instance.terminal.InfoPrintf("load: Failed to load file '%s' with error '%v'\n", args[1], err)
+				instance.terminal.ErrorPrintf("load: Failed to load file '%s' with error '%v'\n", args[1], err)
 			} else {
 				compiled, err := instance.compileScript(bytes)
 				if err != nil {
-					instance.terminal.InfoPrintln(err)
+					instance.terminal.ErrorPrintln(err)
 				} else {
 					instance.manageScript(script.New(
 						strings.TrimSuffix(filepath.Base(args[1]), ".tengo"),
@@ -129,7 +196,7 @@ func (instance *Instance) runCommand(cmd string) bool {
 			instance.terminal.ClearLine()
 			shouldExit, err := instance.PassFileToInput(args[1])
 			if err != nil {
-				instance.terminal.InfoPrintf("load: Failed to load config '%s' with error '%v'\n", args[1], err)
+				instance.terminal.ErrorPrintf("load: Failed to load config '%s' with error '%v'\n", args[1], err)
 			}
 			return shouldExit
 		}
@@ -148,7 +215,7 @@ func (instance *Instance) runCommand(cmd string) bool {
 		// <script>
 		if instance.terminal.RequireArgCount(args, 2) {
 			if len(args[1]) != 1 {
-				instance.terminal.InfoPrintf("bind: %s is an invalid binding; only single character bindings are supported.", args[1])
+				instance.terminal.ErrorPrintf("bind: %s is an invalid binding; only single character bindings are supported.", args[1])
 			} else {
 				instance.terminal.SetBinding([]rune(args[1])[0])
 			}
@@ -159,12 +226,503 @@ func (instance *Instance) runCommand(cmd string) bool {
 		// :echo <message>
 		message := strings.TrimPrefix(cmd, ":echo ")
 		instance.terminal.InfoPrintln(message)
+	case "repl":
+		// while on, each line entered that isn't a command is evaluated like :eval, so variables survive between lines
+		// functions are the exception and only last for the line they are defined in
+		// bindings don't run while the repl is on since characters go to the line instead
+		// :repl <on|off|toggle>
+		if on, ok := instance.parseSwitch(args, instance.terminal.InRepl()); ok {
+			instance.terminal.SetRepl(on)
+			if on {
+				instance.terminal.InfoPrintln("repl: each line is evaluated as tengo until :repl off.")
+			}
+		}
+	case "clear_repl":
+		// forgets the variables defined by :eval and the repl
+		// :clear_repl
+		if instance.terminal.RequireArgCount(args, 1) {
+			instance.ClearReplScope()
+		}
 	case "set_search":
 		// sets instance state current search to the provided regexp
 		// this doesn't do anything alone; the current search needs to be used first
 		// :set_search <regexp>
 		if instance.terminal.RequireArgCount(args, 2) {
 			instance.tree.SetSearch(args[1])
+			instance.tree.Draw()
+		}
+	case "query":
+		// adds every song matching the query to the queue, which is played before the on_no_playback script
+		// the query is everything after the command name; see the query package for the syntax
+		// eg :query artist:"Boards of Canada" year>=1998 ext:flac -path:live
+		// :query <query>
+		count, err := instance.EnqueueQuery(strings.TrimSpace(strings.TrimPrefix(cmd, ":query")))
+		if err != nil {
+			instance.terminal.ErrorPrintln(err)
+		} else {
+			instance.terminal.InfoPrintf("query: enqueued %d songs.\n", count)
+		}
+	case "filter":
+		// shows only the songs matching the query, and the directories containing them, until :clear_filter
+		// the query syntax is the same as for :query
+		// eg :filter genre:ambient -path:live
+		// :filter <query>
+		if err := instance.SetFilter(strings.TrimSpace(strings.TrimPrefix(cmd, ":filter"))); err != nil {
+			instance.terminal.ErrorPrintln(err)
+		}
+	case "clear_filter":
+		// shows every song again after :filter
+		// :clear_filter
+		if instance.terminal.RequireArgCount(args, 1) {
+			instance.ClearFilter()
+		}
+	case "clear_queue":
+		// removes all songs from the queue
+		// :clear_queue
+		if instance.terminal.RequireArgCount(args, 1) {
+			instance.ClearQueue()
+		}
+	case "smart_playlist":
+		// creates a virtual directory inside 'Smart Playlists' at the end of the tree containing every song that matches the query
+		// smart playlists are evaluated again whenever one is added or the library is rescanned
+		// if a smart playlist with the name already exists, its query is replaced
+		// eg :smart_playlist "Recent FLAC" ext:flac added<=30
+		//    :smart_playlist Unplayed plays=0
+		// :smart_playlist <name> <query>
+		if instance.terminal.RequireArgCountGTE(args, 3) {
+			name := strings.Trim(args[1], "\"")
+			if err := instance.AddSmartPlaylist(name, strings.Join(args[2:], " ")); err != nil {
+				instance.terminal.ErrorPrintln(err)
+			}
+		}
+	case "remove_smart_playlist":
+		// :remove_smart_playlist <name>
+		if instance.terminal.RequireArgCount(args, 2) {
+			name := strings.Trim(args[1], "\"")
+			if !instance.RemoveSmartPlaylist(name) {
+				instance.terminal.ErrorPrintf("remove_smart_playlist: no smart playlist named '%s'.\n", name)
+			}
+		}
+	case "rescan":
+		// reads the music directory again to pick up added, removed or retagged files
+		// :rescan
+		if instance.terminal.RequireArgCount(args, 1) {
+			if err := instance.Rescan(); err != nil {
+				instance.terminal.ErrorPrintf("rescan: failed with error '%v'\n", err)
+			}
+		}
+	case "layout":
+		// changes how the panes are arranged
+		// the settings are
+		//     tree_ratio <0-1>            the fraction of the width taken by the tree
+		//     info_ratio <0-1>            the fraction of the column's height taken by the mplayer info pane
+		//     tree_side <left|right>      which side of the screen the tree is on
+		//     order <pane>,<pane>,<pane>  the top to bottom order of the info, output and input panes
+		//     borders <on|off>            whether lines are drawn around and between panes
+		// :layout <setting> <value>
+		if instance.terminal.RequireArgCount(args, 3) {
+			if err := instance.layout.Set(args[1], args[2]); err != nil {
+				instance.terminal.ErrorPrintln(err)
+			} else {
+				instance.ArrangeWindows()
+			}
+		}
+	case "toggle_pane", "show_pane", "hide_pane":
+		// hides or shows a pane, giving its space to the others
+		// eg hiding info, output and input gives the tree the full width
+		// :toggle_pane <info|tree|output|input|status>
+		if instance.terminal.RequireArgCount(args, 2) {
+			pane, err := layout.ParsePane(args[1])
+			if err != nil {
+				instance.terminal.ErrorPrintln(err)
+			} else {
+				switch args[0] {
+				case "toggle_pane":
+					instance.layout.Hidden[pane] = !instance.layout.Hidden[pane]
+				case "show_pane":
+					instance.layout.Hidden[pane] = false
+				case "hide_pane":
+					instance.layout.Hidden[pane] = true
+				}
+				instance.ArrangeWindows()
+			}
+		}
+	case "theme":
+		// switches to one of the builtin color themes, which are default, ember, forest and ocean
+		// terminals without color support only get each element's attributes, eg bold directories
+		// :theme <name>
+		if instance.terminal.RequireArgCount(args, 2) {
+			t, ok := theme.Builtin[args[1]]
+			if !ok {
+				instance.terminal.ErrorPrintf("theme: no theme named '%s'; the themes are %s.\n", args[1], strings.Join(theme.BuiltinNames(), ", "))
+			} else {
+				if err := instance.palette.SetTheme(t); err != nil {
+					instance.terminal.ErrorPrintln(err)
+				}
+				instance.ArrangeWindows()
+			}
+		}
+	case "theme_color":
+		// changes how a single element is drawn, which allows a theme to be defined in the config by setting every element
+		// colors are default, black, red, green, yellow, blue, magenta, cyan, white or a number from 0 to 255 as long as the terminal has that many colors
+		// attributes are a comma separated list of normal, bold, dim, underline, reverse, standout and blink
+		// the elements are directory, song, selection, playing, match, marked, border and error
+		// eg :theme_color directory blue default bold
+		// :theme_color <element> <foreground> <background> <attributes>?
+		if instance.terminal.RequireArgCountGTE(args, 4) {
+			if err := instance.setThemeColor(args[1:]); err != nil {
+				instance.terminal.ErrorPrintln(err)
+			} else {
+				instance.ArrangeWindows()
+			}
+		}
+	case "view":
+		// switches the tree between the filesystem layout and views generated from tags
+		// the builtin views are artist (album artist -> album -> track), genre (genre -> artist -> track) and year (year -> album -> track)
+		// playing from a view still plays the real files
+		// :view <filesystem|artist|genre|year|defined view>
+		if instance.terminal.RequireArgCount(args, 2) {
+			if err := instance.SetView(args[1]); err != nil {
+				instance.terminal.ErrorPrintln(err)
+			}
+		}
+	case "sort", "sort_directory":
+		// sorts the contents of every directory, or of the selected directory and the directories inside it
+		// the modes are lexical (by file name, the default), natural (by name with numbers compared by value), track (by disc and track number),
+		// mtime (most recently modified first), size (smallest first) and year (oldest first); reverse flips the order
+		// directories always come before songs, and :sort_directory default makes the selected directory use the order of its parent again
+		// eg :sort natural
+		//    :sort_directory mtime reverse
+		// :sort <mode> reverse?
+		// :sort_directory <mode|default> reverse?
+		if instance.terminal.RequireArgCountGTE(args, 2) {
+			if args[0] == "sort_directory" && args[1] == "default" && len(args) == 2 {
+				if err := instance.ClearDirectorySortOrder(); err != nil {
+					instance.terminal.ErrorPrintln(err)
+				}
+				break
+			}
+			mode, err := musicarray.ParseSortMode(args[1])
+			if err != nil {
+				instance.terminal.ErrorPrintln(err)
+				break
+			}
+			if len(args) > 3 || len(args) == 3 && args[2] != "reverse" {
+				instance.terminal.ErrorPrintf("%s: expected a sort mode optionally followed by reverse.\n", args[0])
+				break
+			}
+			order := musicarray.SortOrder{Mode: mode, Reverse: len(args) == 3}
+			if args[0] == "sort" {
+				instance.SetSortOrder(order)
+			} else if err := instance.SetDirectorySortOrder(order); err != nil {
+				instance.terminal.ErrorPrintln(err)
+			}
+		}
+	case "define_view":
+		// creates a view that nests songs by each field in order, where a field is anything usable in a query such as a tag name
+		// eg :define_view composer composer album
+		// :define_view <name> <field>+
+		if instance.terminal.RequireArgCountGTE(args, 3) {
+			if err := instance.DefineView(args[1], args[2:]); err != nil {
+				instance.terminal.ErrorPrintln(err)
+			}
+		}
+	case "name_rule":
+		// adds a step to how file and directory names are turned into the names shown in the tree, applied after every existing rule
+		// the rules are strip_extension, strip_track_number, title_case, prettify (10-foo_bar becomes 10 - Foo Bar),
+		// replace <regexp> <replacement> and tengo <script file>, where the script changes the variable name and can check isDir
+		// quote arguments containing spaces, eg :name_rule replace "_" " "
+		// :name_rule <rule> <argument>*
+		if instance.terminal.RequireArgCountGTE(args, 2) {
+			if err := instance.AddNameRule(args[1], args[2:]); err != nil {
+				instance.terminal.ErrorPrintln(err)
+			}
+		}
+	case "clear_name_rules":
+		// removes every naming rule so names are shown as they are on disk, usually followed by some :name_rule commands
+		// :clear_name_rules
+		if instance.terminal.RequireArgCount(args, 1) {
+			instance.SetNaming(musicarray.NamePipeline{})
+		}
+	case "default_name_rules":
+		// goes back to the original naming, which is strip_extension followed by prettify
+		// :default_name_rules
+		if instance.terminal.RequireArgCount(args, 1) {
+			instance.SetNaming(musicarray.DefaultNaming())
+		}
+	case "template":
+		// changes how songs and directories are shown in the tree, what the status bar shows and the titles in exported playlists
+		// %field% is any field usable in a query, %a|b% uses the first field that exists, and text in [] is dropped if a field in it is missing
+		// entries missing a field outside of [] are shown by name, as is everything when the template is left empty
+		// eg :template song [%tracknumber%. ]%title%[ (%duration%)]
+		// the template is everything after the target, so spaces and quotes in it are kept as written
+		// :template <song|directory|status|playlist> <template>?
+		if instance.terminal.RequireArgCountGTE(args, 2) {
+			rest := strings.TrimLeft(strings.TrimSuffix(strings.TrimPrefix(cmd, ":template"), "\n"), " ")
+			template := ""
+			if split := strings.SplitN(rest, " ", 2); len(split) == 2 {
+				template = split[1]
+			}
+			if err := instance.SetTemplate(args[1], template); err != nil {
+				instance.terminal.ErrorPrintln(err)
+			}
+		}
+	case "columns":
+		// shows right aligned columns after each song's name, which is shortened to fit
+		// directories show their song count and total duration in place of the columns, leaving out the count when there is no room for it
+		// the columns are duration, format, bitrate, samplerate, plays and track
+		// eg :columns track,duration,format
+		// :columns <column,column,...|off>
+		if instance.terminal.RequireArgCount(args, 2) {
+			if args[1] == "off" {
+				instance.tree.SetColumns(nil)
+				instance.tree.Redraw()
+			} else if columns, err := dirtree.ParseColumns(args[1]); err != nil {
+				instance.terminal.ErrorPrintln(err)
+			} else {
+				instance.tree.SetColumns(columns)
+				instance.tree.Redraw()
+			}
+		}
+	case "export_playlist", "export_queue":
+		// writes the songs in the selected directory (or the selected song) or the queue to an m3u playlist, titled with the playlist template
+		// :export_playlist <filename>
+		if instance.terminal.RequireArgCount(args, 2) {
+			export := instance.ExportPlaylist
+			if args[0] == "export_queue" {
+				export = instance.ExportQueue
+			}
+			if count, err := export(args[1]); err != nil {
+				instance.terminal.ErrorPrintf("%s: failed with error '%v'\n", args[0], err)
+			} else {
+				instance.terminal.InfoPrintf("%s: wrote %d songs to '%s'.\n", args[0], count, args[1])
+			}
+		}
+	case "toggle_mark":
+		// marks the selected entry, or unmarks it if it is already marked
+		// marked entries are shown with a + and can be acted on together by the *_marked commands and scripts using marked()
+		// :toggle_mark
+		if instance.terminal.RequireArgCount(args, 1) {
+			instance.tree.ToggleMark(instance.tree.CurrentIndex())
+			instance.tree.Draw()
+		}
+	case "mark":
+		// records the selected entry under a name so :goto_mark can select it again later, like marks in vim
+		// :mark <name>
+		if instance.terminal.RequireArgCount(args, 2) {
+			if err := instance.SetNamedMark(args[1]); err != nil {
+				instance.terminal.ErrorPrintln(err)
+			}
+		}
+	case "goto_mark":
+		// selects the entry recorded with :mark <name>, opening the directories it is in
+		// :goto_mark <name>
+		if instance.terminal.RequireArgCount(args, 2) {
+			if err := instance.GotoNamedMark(args[1]); err != nil {
+				instance.terminal.ErrorPrintln(err)
+			}
+		}
+	case "delete_mark":
+		// forgets a named mark
+		// :delete_mark <name>
+		if instance.terminal.RequireArgCount(args, 2) {
+			if err := instance.DeleteNamedMark(args[1]); err != nil {
+				instance.terminal.ErrorPrintln(err)
+			}
+		}
+	case "marks":
+		// lists the named marks and the paths they are for
+		// :marks
+		if instance.terminal.RequireArgCount(args, 1) {
+			names := instance.namedMarkNames()
+			if len(names) == 0 {
+				instance.terminal.InfoPrintln("marks: there are no named marks.")
+			}
+			for _, name := range names {
+				instance.terminal.InfoPrintf("%s\t%s\n", name, instance.namedMarks[name])
+			}
+		}
+	case "marks_file":
+		// loads named marks from the file and saves them to it whenever they change, so they are kept between sessions
+		// the file doesn't need to exist yet
+		// :marks_file <filename>
+		if instance.terminal.RequireArgCount(args, 2) {
+			if err := instance.LoadNamedMarks(args[1]); err != nil {
+				instance.terminal.ErrorPrintln(err)
+			}
+		}
+	case "store":
+		// lists the values scripts have put in the store with store.set
+		// :store
+		if instance.terminal.RequireArgCount(args, 1) {
+			keys := instance.storeKeys()
+			if len(keys) == 0 {
+				instance.terminal.InfoPrintln("store: nothing is stored.")
+			}
+			for _, key := range keys {
+				instance.terminal.InfoPrintf("%s\t%s\n", key, fromStored(instance.store[key]))
+			}
+		}
+	case "store_file":
+		// loads the store scripts share from the json file and saves it there whenever it changes, so values are kept between sessions
+		// the file doesn't need to exist yet
+		// :store_file <filename>
+		if instance.terminal.RequireArgCount(args, 2) {
+			if err := instance.LoadStore(args[1]); err != nil {
+				instance.terminal.ErrorPrintln(err)
+			}
+		}
+	case "mark_range":
+		// marks every entry shown between the entry last marked with :toggle_mark and the selected one
+		// :mark_range
+		if instance.terminal.RequireArgCount(args, 1) {
+			instance.tree.MarkRange(instance.tree.CurrentIndex())
+			instance.tree.Draw()
+		}
+	case "mark_matches":
+		// marks every entry matching the current search
+		// :mark_matches
+		if instance.terminal.RequireArgCount(args, 1) {
+			count := instance.tree.MarkMatches()
+			instance.tree.Draw()
+			instance.terminal.InfoPrintf("mark_matches: marked %d entries.\n", count)
+		}
+	case "clear_marks":
+		// unmarks every entry
+		// :clear_marks
+		if instance.terminal.RequireArgCount(args, 1) {
+			instance.tree.ClearMarks()
+			instance.tree.Draw()
+		}
+	case "enqueue_marked":
+		// adds the marked songs and every song inside marked directories to the queue
+		// :enqueue_marked
+		if instance.terminal.RequireArgCount(args, 1) {
+			instance.terminal.InfoPrintf("enqueue_marked: enqueued %d songs.\n", instance.EnqueueMarked())
+		}
+	case "export_marked":
+		// writes the marked songs and every song inside marked directories to an m3u playlist, titled with the playlist template
+		// :export_marked <filename>
+		if instance.terminal.RequireArgCount(args, 2) {
+			if count, err := instance.ExportMarked(args[1]); err != nil {
+				instance.terminal.ErrorPrintf("%s: failed with error '%v'\n", args[0], err)
+			} else {
+				instance.terminal.InfoPrintf("%s: wrote %d songs to '%s'.\n", args[0], count, args[1])
+			}
+		}
+	case "expand_marked", "collapse_marked":
+		// opens or closes every marked directory
+		// :expand_marked
+		// :collapse_marked
+		if instance.terminal.RequireArgCount(args, 1) {
+			instance.ExpandMarked(args[0] == "expand_marked")
+		}
+	case "jump_to_playing":
+		// selects the song being played, opening the directories it is in
+		// :jump_to_playing
+		if instance.terminal.RequireArgCount(args, 1) {
+			if !instance.JumpToPlaying() {
+				instance.terminal.InfoPrintln("jump_to_playing: nothing is playing.")
+			}
+		}
+	case "follow_playback":
+		// when on, which it is by default, the selection moves to each song as it starts playing
+		// when off, the selection stays wherever it was left and the playing song is only marked
+		// :follow_playback <on|off|toggle>
+		if on, ok := instance.parseSwitch(args, instance.followPlayback); ok {
+			instance.followPlayback = on
+		}
+	case "mouse":
+		// when on, which it is by default, the tree and the progress bar in the status bar respond to the mouse
+		// turning it off lets the terminal select text with the mouse again
+		// :mouse <on|off|toggle>
+		if on, ok := instance.parseSwitch(args, instance.mouse); ok {
+			instance.SetMouse(on)
+		}
+	case "tree_header":
+		// when on, which it is by default, the top row of the tree names the directories the selection is in and shows how far down the tree the view is
+		// :tree_header <on|off|toggle>
+		if on, ok := instance.parseSwitch(args, instance.tree.HasHeader()); ok {
+			instance.tree.SetHeader(on)
+			instance.tree.Redraw()
+		}
+	case "tree_scrollbar":
+		// when on, which it is by default, the right column of the tree shows a scrollbar
+		// :tree_scrollbar <on|off|toggle>
+		if on, ok := instance.parseSwitch(args, instance.tree.HasScrollbar()); ok {
+			instance.tree.SetScrollbar(on)
+			instance.tree.Redraw()
+		}
+	case "stats_file":
+		// loads play counts from the file and saves them to it whenever a song is played
+		// the file doesn't need to exist yet
+		// :stats_file <filename>
+		if instance.terminal.RequireArgCount(args, 2) {
+			if err := instance.LoadStats(args[1]); err != nil {
+				instance.terminal.ErrorPrintln(err)
+			}
+		}
+	case "select_down", "select_up", "page_down", "page_up", "half_page_down", "half_page_up", "select_top", "select_bottom", "next_sibling_dir", "prev_sibling_dir", "first_child", "last_child":
+		// moves the selection, count times or by count pages where that makes sense
+		// sibling directories are the ones at the same depth as the selected directory, or as the one containing the selected song
+		// first_child and last_child select the first or last entry directly inside the selected directory
+		// in bindings, the count typed before the key is available to scripts as count(), eg selectDown(count())
+		// :select_down <count>?
+		// :page_down <count>?
+		// :half_page_down <count>?
+		// :select_top
+		// :next_sibling_dir <count>?
+		// :first_child
+		if n, ok := findNavigation(args[0]); ok {
+			instance.runNavigation(n, args)
+		}
+	case "expand_all", "collapse_all", "collapse_others":
+		// opens every directory, closes every directory or closes every directory except those containing the selection
+		// :expand_all
+		// :collapse_all
+		// :collapse_others
+		if instance.terminal.RequireArgCount(args, 1) {
+			switch args[0] {
+			case "expand_all":
+				instance.tree.ExpandAll()
+			case "collapse_all":
+				instance.tree.CollapseAll()
+			case "collapse_others":
+				instance.tree.CollapseOthers()
+			}
+			instance.tree.Draw()
+		}
+	case "expand_to_depth":
+		// opens every directory shallower than the depth and closes the rest, where the root directory is at depth 0
+		// eg :expand_to_depth 2 shows the root, the directories in it and their contents
+		// :expand_to_depth <depth>
+		if instance.terminal.RequireArgCount(args, 2) {
+			if depth, err := strconv.Atoi(args[1]); err != nil || depth < 0 {
+				instance.terminal.ErrorPrintf("expand_to_depth: the depth must be a number of at least 0 but recieved '%s'.\n", args[1])
+			} else {
+				instance.tree.ExpandToDepth(depth)
+				instance.tree.Draw()
+			}
+		}
+	case "expand_recursive":
+		// opens the selected directory along with every directory inside it
+		// :expand_recursive
+		if instance.terminal.RequireArgCount(args, 1) {
+			if !instance.tree.ExpandRecursive(instance.tree.CurrentIndex()) {
+				instance.terminal.ErrorPrintln("expand_recursive: the selection isn't a directory.")
+			}
+			instance.tree.Draw()
+		}
+	case "jump_to_letter":
+		// selects the next entry shown whose name starts with the letter, ignoring case and wrapping around to the top
+		// :jump_to_letter <letter>
+		if instance.terminal.RequireArgCount(args, 2) {
+			if letter := []rune(args[1]); len(letter) != 1 {
+				instance.terminal.ErrorPrintf("jump_to_letter: expected a single letter but recieved '%s'.\n", args[1])
+			} else {
+				instance.tree.JumpToLetter(letter[0])
+				instance.tree.Draw()
+			}
 		}
 	case "alias":
 		// binds a command (and optionally some arguments) to a new name
@@ -182,7 +740,7 @@ func (instance *Instance) runCommand(cmd string) bool {
 		if ok {
 			shouldExit, err := instance.PassFileToInput(configFile)
 			if err != nil {
-				instance.terminal.InfoPrintf("load: Failed to load config '%s' with error '%v'\n", configFile, err)
+				instance.terminal.ErrorPrintf("load: Failed to load config '%s' with error '%v'\n", configFile, err)
 			}
 			return shouldExit
 		}
@@ -198,11 +756,28 @@ func (instance *Instance) runCommand(cmd string) bool {
 			}
 		}
 
-		instance.terminal.InfoPrintf("Unknown command: '%s'\n", args[0])
+		instance.terminal.ErrorPrintf("Unknown command: '%s'\n", args[0])
 	}
 	return false
 }
 
+// parseSwitch reads the argument of a command taking on, off or toggle, where current is the setting being changed
+func (instance *Instance) parseSwitch(args []string, current bool) (bool, bool) {
+	if !instance.terminal.RequireArgCount(args, 2) {
+		return current, false
+	}
+	switch args[1] {
+	case "on":
+		return true, true
+	case "off":
+		return false, true
+	case "toggle":
+		return !current, true
+	}
+	instance.terminal.ErrorPrintf("%s: expected on, off or toggle but recieved '%s'.\n", args[0], args[1])
+	return current, false
+}
+
 // splitCommand parses a command into its name and arguments
 func splitCommand(cmd string) ([]string, error) {
 	// current rules:
@@ -0,0 +1,144 @@
+package dirtree
+
+import (
+	"github.com/StructsNotClasses/mim/musicarray"
+	"github.com/StructsNotClasses/mim/textwidth"
+	"github.com/StructsNotClasses/mim/titleformat"
+
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type Column string
+
+const (
+	DurationColumn   Column = "duration"
+	FormatColumn     Column = "format"
+	BitrateColumn    Column = "bitrate"
+	SampleRateColumn Column = "samplerate"
+	PlaysColumn      Column = "plays"
+	TrackColumn      Column = "track"
+)
+
+// columnWidths are wide enough for any normal value, eg 1:02:03, flac, 1411k, 44.1k and 999
+var columnWidths = map[Column]int{
+	DurationColumn:   7,
+	FormatColumn:     4,
+	BitrateColumn:    5,
+	SampleRateColumn: 5,
+	PlaysColumn:      4,
+	TrackColumn:      3,
+}
+
+// minNameWidth is the narrowest the name column can get before the other columns are left out
+const minNameWidth = 12
+
+// ParseColumns reads a comma separated list of columns, eg duration,format,plays
+func ParseColumns(list string) ([]Column, error) {
+	columns := []Column{}
+	for _, name := range strings.Split(list, ",") {
+		column := Column(name)
+		if _, ok := columnWidths[column]; !ok {
+			return nil, errors.New(fmt.Sprintf("columns: '%s' is not a column; the columns are duration, format, bitrate, samplerate, plays and track.", name))
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// SetColumns shows the columns to the right of each name, or only names if there are none
+func (t *DirTree) SetColumns(columns []Column) {
+	t.columns = columns
+}
+
+// columnsWidth returns the number of columns taken up by the columns, including a space before each of them
+func (t DirTree) columnsWidth() int {
+	width := 0
+	for _, column := range t.columns {
+		width += columnWidths[column] + 1
+	}
+	return width
+}
+
+// withColumns fits the name into the space left by the columns and appends them, right aligned
+// directories show how many songs they contain and their total duration across the columns instead
+func (t DirTree) withColumns(name string, index, width int) string {
+	columnsWidth := t.columnsWidth()
+	if len(t.columns) == 0 || width-columnsWidth < minNameWidth {
+		return truncate(name, width)
+	}
+
+	e := t.array[index]
+	if e.Type == musicarray.DirectoryEntry {
+		return t.withSummary(name, index, width, columnsWidth)
+	}
+	columns := ""
+	for _, column := range t.columns {
+		columns += " " + alignRight(songColumn(e, column), columnWidths[column])
+	}
+	return textwidth.Pad(name, width-columnsWidth) + columns
+}
+
+// withSummary fits the name of the directory at index next to how many songs it contains and their total duration
+// the summary takes the space of the columns, or more of the name's space down to minNameWidth when it's wider
+// the song count is left out first when there isn't room for both
+func (t DirTree) withSummary(name string, index, width, columnsWidth int) string {
+	songs, duration := t.summary.Directory(t.array, index)
+	count := fmt.Sprintf("%d songs", songs)
+	summary := count
+	if duration > 0 {
+		summary = count + " " + titleformat.FormatDuration(duration)
+	}
+
+	room := width - minNameWidth - 1
+	if textwidth.String(summary) > room && duration > 0 {
+		summary = titleformat.FormatDuration(duration)
+	}
+	summaryWidth := textwidth.String(summary)
+	if summaryWidth > room {
+		summaryWidth = room
+	}
+	if summaryWidth < columnsWidth-1 {
+		summaryWidth = columnsWidth - 1
+	}
+	return textwidth.Pad(name, width-summaryWidth-1) + " " + alignRight(summary, summaryWidth)
+}
+
+func songColumn(e musicarray.Entry, column Column) string {
+	switch column {
+	case DurationColumn:
+		if e.Song.Duration > 0 {
+			return titleformat.FormatDuration(e.Song.Duration)
+		}
+	case FormatColumn:
+		return strings.ToLower(strings.TrimPrefix(filepath.Ext(e.Path), "."))
+	case BitrateColumn:
+		if e.Song.Bitrate > 0 {
+			return fmt.Sprintf("%dk", e.Song.Bitrate)
+		}
+	case SampleRateColumn:
+		if e.Song.SampleRate > 0 {
+			return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(e.Song.SampleRate)/1000), ".0") + "k"
+		}
+	case PlaysColumn:
+		if e.Song.Stats != nil {
+			return fmt.Sprint(e.Song.Stats.PlayCount)
+		}
+	case TrackColumn:
+		track := e.Song.Tags["tracknumber"]
+		// track numbers are often written as 3/12
+		if slash := strings.IndexByte(track, '/'); slash != -1 {
+			track = track[:slash]
+		}
+		return track
+	}
+	return ""
+}
+
+// alignRight truncates s to width columns and pads it on the left
+func alignRight(s string, width int) string {
+	s = textwidth.Truncate(s, width)
+	return spaces(width-textwidth.String(s)) + s
+}
@@ -1,7 +1,9 @@
 package dirtree
 
 import (
+	"github.com/StructsNotClasses/mim/instance/theme"
 	"github.com/StructsNotClasses/mim/musicarray"
+	"github.com/StructsNotClasses/mim/titleformat"
 
 	gnc "github.com/rthornton128/goncurses"
 
@@ -13,21 +15,141 @@ type DirTree struct {
 	currentIndex  int
 	array         musicarray.MusicArray
 	currentSearch string
+	// the most recently played song, which is only marked while playing is true
+	playingIndex int
+	playingPath  string
+	// the path of the directory the song was played from, which tells apart copies of it such as one in a smart playlist
+	playingParent string
+	playing       bool
+	palette       *theme.Palette
+	// marked entries are kept by path so the marks survive the array being replaced
+	// an entry that appears more than once, such as a song in a smart playlist, is marked everywhere it appears
+	marked     map[string]bool
+	markAnchor string
+
+	// entries are shown by name while the templates are empty
+	songTemplate      titleformat.Template
+	directoryTemplate titleformat.Template
+	columns           []Column
+	summary           musicarray.Summary
+
+	// rendering state kept between draws
+	top         int
+	visible     []int
+	rows        []Line
+	drawnWidth  int
+	drawnHeight int
+	hidden      bool
+	header      bool
+	scrollbar   bool
+
+	// the number of rows that can be displayed and how many of them are above countedTop, the first row of the last draw
+	// they are kept up to date as directories open and close so the header and scrollbar don't walk the whole tree on every draw
+	// rowsCounted is false when they have to be counted again, eg after the array is replaced
+	rowCount    int
+	rowsAbove   int
+	countedTop  int
+	rowsCounted bool
 }
 
-func New(win *gnc.Window, arr musicarray.MusicArray) DirTree {
+func New(win *gnc.Window, arr musicarray.MusicArray, palette *theme.Palette) DirTree {
 	return DirTree{
 		win:           win,
 		currentIndex:  0,
 		array:         arr,
 		currentSearch: "",
+		playingIndex:  -1,
+		palette:       palette,
+		marked:        make(map[string]bool),
+		header:        true,
+		scrollbar:     true,
+		summary:       arr.Summarize(),
+	}
+}
+
+// SetPlaying marks the song at index as the one being played
+// the path is kept as well so the song can be found again when the array is replaced
+func (t *DirTree) SetPlaying(index int) {
+	if t.IsInRange(index) && t.array[index].Type == musicarray.SongEntry {
+		t.playingIndex = index
+		t.playingPath = t.array[index].Path
+		t.playingParent = t.parentPath(index)
+		t.playing = true
+	}
+}
+
+// StopPlaying removes the mark from the playing song, which is still remembered as the last one played
+func (t *DirTree) StopPlaying() {
+	t.playing = false
+}
+
+// PlayingIndex returns the index of the song being played, which is separate from the selection, or -1 if nothing is playing
+func (t DirTree) PlayingIndex() int {
+	if !t.playing {
+		return -1
 	}
+	return t.playingIndex
+}
+
+// LastPlayedIndex returns the index of the song being played or the one that played most recently, or -1 if nothing has played
+// this is where playback continues from, regardless of where the selection was moved in the meantime
+func (t DirTree) LastPlayedIndex() int {
+	return t.playingIndex
+}
+
+func (t *DirTree) SetSongTemplate(template titleformat.Template) {
+	t.songTemplate = template
+}
+
+func (t *DirTree) SetDirectoryTemplate(template titleformat.Template) {
+	t.directoryTemplate = template
 }
 
 func (t *DirTree) Toggle(index int) error {
 	if t.array[index].Type != musicarray.DirectoryEntry {
 		return errors.New("dirtree.Toggle: can only toggle directories.")
 	}
-	t.array[index].Dir.ManuallyExpanded = !t.array[index].Dir.ManuallyExpanded
+	t.setManuallyExpanded(index, !t.array[index].Dir.ManuallyExpanded)
 	return nil
 }
+
+// SetArray replaces the entries of the tree, keeping directories that were manually expanded open and the selection on the same path if they still exist
+func (t *DirTree) SetArray(arr musicarray.MusicArray) {
+	expanded := make(map[string]bool)
+	for _, e := range t.array {
+		if e.Type == musicarray.DirectoryEntry && e.Dir.ManuallyExpanded {
+			expanded[e.Path] = true
+		}
+	}
+	selectedPath, selectedParent := "", ""
+	if t.IsInRange(t.currentIndex) {
+		selectedPath = t.array[t.currentIndex].Path
+		selectedParent = t.parentPath(t.currentIndex)
+	}
+
+	t.array = arr
+	t.summary = arr.Summarize()
+	t.rowsCounted = false
+	for i := range t.array {
+		if t.array[i].Type == musicarray.DirectoryEntry {
+			t.array[i].Dir.ManuallyExpanded = expanded[t.array[i].Path]
+			t.array[i].Dir.AutoExpanded = false
+		}
+	}
+
+	t.playingIndex = -1
+	if t.playingPath != "" {
+		if index, ok := t.IndexOfPathIn(t.playingPath, t.playingParent); ok {
+			t.playingIndex = index
+		}
+	}
+
+	t.currentIndex = 0
+	t.top = 0
+	t.visible = []int{}
+	if index, ok := t.IndexOfPathIn(selectedPath, selectedParent); ok {
+		t.Select(index)
+	} else {
+		t.Select(0)
+	}
+}
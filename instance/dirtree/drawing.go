@@ -1,155 +1,382 @@
 package dirtree
 
 import (
+	"github.com/StructsNotClasses/mim/instance/theme"
 	"github.com/StructsNotClasses/mim/musicarray"
+	"github.com/StructsNotClasses/mim/textwidth"
+	"github.com/StructsNotClasses/mim/titleformat"
 
 	"strings"
-
-	gnc "github.com/rthornton128/goncurses"
 )
 
 type Line struct {
 	contents   string
 	isSelected bool
 	isDir      bool
+	isPlaying  bool
+	isMatch    bool
+	isMarked   bool
 }
 
+// Draw renders only the entries that fit in the window
+// the first visible entry is kept between draws so the view only scrolls when the selection leaves it, and only rows that differ from the last draw are printed
 func (t *DirTree) Draw() {
-	t.win.Erase()
+	if t.hidden {
+		return
+	}
 	defer t.win.Refresh()
 
 	height, width := t.win.MaxYX()
+	if width != t.drawnWidth || height != t.drawnHeight {
+		// every row is stale after a resize
+		t.win.Erase()
+		t.rows = nil
+		t.drawnWidth = width
+		t.drawnHeight = height
+	}
+
+	listY, listHeight, listWidth := t.listArea()
+	t.visible = t.visibleWindow(listHeight)
+
+	lines := make([]Line, len(t.visible))
+	for y, index := range t.visible {
+		lines[y] = t.line(index, listWidth)
+	}
+
+	for y := 0; y < listHeight; y++ {
+		if y < len(lines) {
+			if y >= len(t.rows) || t.rows[y] != lines[y] {
+				t.printLine(lines[y], listY+y)
+			}
+		} else if y < len(t.rows) {
+			t.win.Move(listY+y, 0)
+			t.win.ClearToEOL()
+		}
+	}
+	t.rows = lines
+
+	if listY > 0 || listWidth < width {
+		// counting the rows walks every one that can be displayed, so it is done once for both
+		scroll := t.scrollState()
+		if listY > 0 {
+			t.drawHeader(width, scroll)
+		}
+		if listWidth < width {
+			t.drawScrollbar(listY, listHeight, width-1, scroll)
+		}
+	}
+}
+
+// listArea returns the first row entries are drawn on and the size of the area they take, which leaves room for the header and the scrollbar
+// both are left out of windows too small to fit them alongside any entries
+func (t DirTree) listArea() (y, height, width int) {
+	height, width = t.win.MaxYX()
+	if t.header && height > 1 {
+		y, height = 1, height-1
+	}
+	if t.scrollbar && width > 1 {
+		width--
+	}
+	return y, height, width
+}
+
+// RowIndex returns the index of the entry drawn on row y of the window during the last draw
+func (t DirTree) RowIndex(y int) (int, bool) {
+	listY, _, _ := t.listArea()
+	row := y - listY
+	if row < 0 || row >= len(t.visible) {
+		return -1, false
+	}
+	return t.visible[row], true
+}
+
+// VisibleIndices returns the index of the entry drawn on each row of the window during the last draw
+func (t DirTree) VisibleIndices() []int {
+	return t.visible
+}
+
+// Scroll moves the view by a number of rows, downwards for positive counts, without scrolling past the last entry
+// the selection is moved to the nearest row still shown if it would leave the window, since the view always follows the selection
+func (t *DirTree) Scroll(rows int) {
+	_, height, _ := t.listArea()
+	if len(t.array) == 0 || height <= 0 {
+		return
+	}
+	top := 0
+	if t.IsInRange(t.top) {
+		top = t.visibleAncestor(t.top)
+	}
+	for ; rows > 0; rows-- {
+		next := t.nextVisible(top)
+		if next == -1 || len(t.rowsFrom(next, height)) < height {
+			break
+		}
+		top = next
+	}
+	for ; rows < 0; rows++ {
+		prev := t.prevVisible(top)
+		if prev == -1 {
+			break
+		}
+		top = prev
+	}
+
+	t.top = top
+	shown := t.rowsFrom(top, height)
+	if !containsIndex(shown, t.currentIndex) {
+		if t.currentIndex < top {
+			t.Select(shown[0])
+		} else {
+			t.Select(shown[len(shown)-1])
+		}
+	}
+}
+
+// visibleWindow finds the entries to draw, starting from the previous first row if the selection can still be seen from it
+func (t *DirTree) visibleWindow(height int) []int {
+	if len(t.array) == 0 || height <= 0 {
+		return []int{}
+	}
+
+	if t.IsInRange(t.top) {
+		t.top = t.visibleAncestor(t.top)
+		rows := t.rowsFrom(t.top, height)
+		if containsIndex(rows, t.currentIndex) {
+			return t.fill(rows, height)
+		}
 
-	lines, selectedLine := t.getLines(width)
+		// scroll by a single row if the selection moved just outside of the window
+		if len(rows) > 0 && t.currentIndex == t.nextVisible(rows[len(rows)-1]) {
+			t.top = t.nextVisible(t.top)
+			return t.fill(t.rowsFrom(t.top, height), height)
+		} else if t.currentIndex == t.prevVisible(t.top) {
+			t.top = t.currentIndex
+			return t.fill(t.rowsFrom(t.top, height), height)
+		}
+	}
+
+	// otherwise center the selection
+	t.top = t.currentIndex
+	for above := 0; above < height/2; above++ {
+		prev := t.prevVisible(t.top)
+		if prev == -1 {
+			break
+		}
+		t.top = prev
+	}
+	return t.fill(t.rowsFrom(t.top, height), height)
+}
+
+// fill moves the first row upwards while there is empty space at the bottom of the window
+func (t *DirTree) fill(rows []int, height int) []int {
+	for len(rows) < height {
+		prev := t.prevVisible(t.top)
+		if prev == -1 {
+			break
+		}
+		t.top = prev
+		rows = append([]int{prev}, rows...)
+	}
+	return rows
+}
+
+// rowsFrom returns up to count visible entries starting with start
+func (t DirTree) rowsFrom(start, count int) []int {
+	rows := make([]int, 0, count)
+	for i := start; i != -1 && len(rows) < count; i = t.nextVisible(i) {
+		rows = append(rows, i)
+	}
+	return rows
+}
 
-	centerLine := int(height / 2)
-	if len(lines) <= height || selectedLine < centerLine {
-		printLines(t.win, lines, selectedLine)
-	} else if selectedLine >= (len(lines) - (height - centerLine)) {
-		offsetIndex := selectedLine + height - len(lines) + 1
-		printLines(t.win, lines[len(lines)-height-1:], offsetIndex)
+// nextVisible returns the entry displayed after index, skipping the contents of closed directories, or -1 if there isn't one
+func (t DirTree) nextVisible(index int) int {
+	next := index + 1
+	if t.array[index].Type == musicarray.DirectoryEntry && !t.array[index].Dir.Expanded() {
+		next = t.array[index].Dir.EndDirectoryIndex
+	}
+	if next >= len(t.array) {
+		return -1
+	}
+	return next
+}
+
+// prevVisible returns the entry displayed before index or -1 if there isn't one
+// the entry before index in the array is hidden if it's inside a closed directory that is at least as deep as index, in which case the shallowest such directory is the one displayed
+func (t DirTree) prevVisible(index int) int {
+	if index <= 0 {
+		return -1
+	}
+	candidate := index - 1
+	depth := t.array[index].Depth
+	for p := t.array[candidate].ParentIndex; p != -1 && t.array[p].Depth >= depth; p = t.array[p].ParentIndex {
+		if !t.array[p].Dir.Expanded() {
+			candidate = p
+		}
+	}
+	return candidate
+}
+
+// visibleAncestor returns index if it is displayed or otherwise the closed directory hiding it
+func (t DirTree) visibleAncestor(index int) int {
+	result := index
+	for p := t.array[index].ParentIndex; p != -1; p = t.array[p].ParentIndex {
+		if !t.array[p].Dir.Expanded() {
+			result = p
+		}
+	}
+	return result
+}
+
+func containsIndex(indices []int, index int) bool {
+	for _, i := range indices {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
+
+func (t DirTree) line(index, width int) Line {
+	e := t.array[index]
+	isSelected := index == t.currentIndex
+	l := Line{
+		isSelected: isSelected,
+		isPlaying:  t.isPlaying(index),
+		isMatch:    t.isMatch(index),
+		isMarked:   t.IsMarked(index),
+	}
+	if e.Type == musicarray.DirectoryEntry {
+		name := titleformat.FormatEntry(t.directoryTemplate, t.array, index)
+		l.contents = t.withColumns(dirNameToString(e.Depth, name, e.Dir.Expanded(), isSelected, l.isMarked), index, width)
+		l.isDir = true
 	} else {
-		printLines(t.win, lines[selectedLine-centerLine:selectedLine+(height-centerLine)], centerLine)
+		name := titleformat.FormatEntry(t.songTemplate, t.array, index)
+		l.contents = t.withColumns(songToString(e.Depth, name, isSelected, l.isPlaying, l.isMarked), index, width)
 	}
+	return l
 }
 
-// printLines prints the provided slice of strings one at a time. The first item in the slice will be printed at y = 0 on the window, second at y = 1, and so on until out of slice items or height reached
-func printLines(win *gnc.Window, lines []Line, selectedLine int) {
-	height, _ := win.MaxYX()
-	for i := 0; i < len(lines) && i <= height; i++ {
-		printLine(win, lines[i], i)
+// isPlaying reports whether the entry is the song being played or a closed directory hiding it
+func (t DirTree) isPlaying(index int) bool {
+	playing := t.PlayingIndex()
+	if playing == -1 {
+		return false
 	}
+	e := t.array[index]
+	if e.Type == musicarray.DirectoryEntry {
+		return !e.Dir.Expanded() && playing > index && playing < e.Dir.EndDirectoryIndex
+	}
+	return index == playing
 }
 
-func printLine(win *gnc.Window, line Line, y int) {
-	const dirAttributes = gnc.A_BOLD
-	const fileAttributes = gnc.A_NORMAL
-	const selectedNameAttributes = gnc.A_STANDOUT
+// printLine replaces row y of the window with the line, styled according to the theme
+func (t DirTree) printLine(line Line, y int) {
+	t.win.Move(y, 0)
+	t.win.ClearToEOL()
+
+	element := theme.Song
+	if line.isDir {
+		element = theme.Directory
+	}
+	if line.isMatch {
+		element = theme.Match
+	}
+	if line.isMarked {
+		element = theme.Marked
+	}
+	if line.isPlaying {
+		element = theme.Playing
+	}
 
 	if line.isSelected {
+		// the pointer keeps the entry's own style while the name is highlighted
 		pointerIndex := strings.Index(line.contents, "=>") + 2
 		if len(line.contents) > pointerIndex {
-			win.AttrOn(gnc.A_BOLD)
-			win.MovePrint(y, 0, line.contents[:pointerIndex])
-			win.AttrOff(gnc.A_BOLD)
-
-			win.AttrOn(selectedNameAttributes)
-			win.Println(line.contents[pointerIndex:])
-			win.AttrOff(selectedNameAttributes)
+			t.printStyled(y, 0, line.contents[:pointerIndex], element)
+			t.printStyled(y, pointerIndex, line.contents[pointerIndex:], theme.Selection)
 		}
-	} else if line.isDir {
-		win.AttrOn(dirAttributes)
-		win.MovePrintln(y, 0, line.contents)
-		win.AttrOff(dirAttributes)
 	} else {
-		win.AttrOn(fileAttributes)
-		win.MovePrintln(y, 0, line.contents)
-		win.AttrOff(fileAttributes)
+		t.printStyled(y, 0, line.contents, element)
 	}
 }
 
-func (t *DirTree) getLines(width int) ([]Line, int) {
-	result := []Line{}
-	selectedLine := 0
-	for i := 0; i < len(t.array); {
-		isSelected := i == t.currentIndex
-		if isSelected {
-			selectedLine = len(result)
-		}
-		if t.array[i].Type == musicarray.DirectoryEntry {
-			isOpen := t.array[i].Dir.AutoExpanded || t.array[i].Dir.ManuallyExpanded
-			result = append(result, Line{
-				contents:   dirNameToString(width, t.array[i].Depth, t.array[i].Name, isOpen, isSelected),
-				isSelected: isSelected,
-				isDir:      true,
-			})
-			if isOpen {
-				i++
-			} else {
-				i = t.array[i].Dir.EndDirectoryIndex
-			}
-		} else {
-			result = append(result, Line{
-				contents:   songToString(width, t.array[i].Depth, t.array[i].Name, isSelected),
-				isSelected: isSelected,
-				isDir:      false,
-			})
-			i++
-		}
-	}
-	return result, selectedLine
+func (t DirTree) printStyled(y, x int, s string, element theme.Element) {
+	attributes := t.palette.Attributes(element)
+	t.win.AttrOn(attributes)
+	t.win.MovePrint(y, x, s)
+	t.win.AttrOff(attributes)
 }
 
-func dirNameToString(width, indent int, name string, isOpen, isSelected bool) string {
+func dirNameToString(indent int, name string, isOpen, isSelected, isMarked bool) string {
 	leadChars := "> "
 	if isOpen {
 		leadChars = "v "
 	}
 	if isSelected {
-		return spaces(indent) + truncate("=>"+name, width-indent)
+		return spaces(indent) + "=>" + name
 	} else {
-		return spaces(indent) + truncate(leadChars+name, width-indent)
+		return spaces(indent) + markLead(leadChars, isMarked) + name
 	}
 }
 
-func songToString(width, indent int, name string, isSelected bool) string {
-	leadChar := "o "
+func songToString(indent int, name string, isSelected, isPlaying, isMarked bool) string {
+	leadChar := markLead("o ", isMarked)
+	if isPlaying {
+		leadChar = markLead("* ", isMarked)
+	}
 	if isSelected {
 		leadChar = "=>"
 	}
-	return spaces(indent) + truncate(leadChar+name, width-indent)
+	return spaces(indent) + leadChar + name
 }
 
-func spaces(count int) string {
-	s := ""
-	for i := 0; i < count; i++ {
-		s += " "
+// markLead puts a plus after the lead character of marked entries so they can be told apart without colors
+func markLead(lead string, isMarked bool) string {
+	if isMarked {
+		return lead[:1] + "+"
 	}
-	return s
+	return lead
 }
 
-func printDirName(win *gnc.Window, y int, x int, name string, isOpen, isSelected bool) {
-	_, w := win.MaxYX()
-	var leadChars string
-	if isOpen {
-		leadChars = "v "
-	} else {
-		leadChars = "> "
-	}
-	if isSelected {
-		win.AttrOn(gnc.A_STANDOUT)
-		win.AttrOff(gnc.A_NORMAL)
-		win.MovePrintln(y, x, truncate("=>"+name, w-x))
-		win.AttrOff(gnc.A_STANDOUT)
-		win.AttrOn(gnc.A_NORMAL)
-	} else {
-		win.MovePrintln(y, x, truncate(leadChars+name, w-x))
-	}
+func spaces(count int) string {
+	return strings.Repeat(" ", count)
 }
 
+// truncate shortens s to fit in l columns, marking names that were cut with an ellipsis
 func truncate(s string, l int) string {
-	if len(s) > l {
-		return s[:l]
+	return textwidth.Truncate(s, l)
+}
+
+// Redraw draws every row again rather than only those that changed, for when the window contents were lost
+func (t *DirTree) Redraw() {
+	if t.hidden {
+		return
 	}
-	return s
+	t.win.Erase()
+	t.rows = nil
+	t.Draw()
+}
+
+// SetHeader shows or hides the row at the top of the window naming the directories the selection is in
+func (t *DirTree) SetHeader(shown bool) {
+	t.header = shown
+}
+
+func (t DirTree) HasHeader() bool {
+	return t.header
+}
+
+// SetScrollbar shows or hides the scrollbar on the right edge of the window
+func (t *DirTree) SetScrollbar(shown bool) {
+	t.scrollbar = shown
+}
+
+func (t DirTree) HasScrollbar() bool {
+	return t.scrollbar
+}
+
+// SetHidden stops the tree from drawing while its pane isn't shown
+func (t *DirTree) SetHidden(hidden bool) {
+	t.hidden = hidden
 }
@@ -0,0 +1,137 @@
+package dirtree
+
+import (
+	"reflect"
+	"testing"
+)
+
+// the trees used below, by index, where only the root starts expanded:
+//
+// testTree(2, 2, 2)       testTree(2, 1, 3)
+//  0 root                   0 root
+//  1   d0                   1   d0
+//  2     s0                 2     d0
+//  3     s1                 3       s0
+//  4   d1                   4     d1
+//  5     s0                 5       s0
+//  6     s1                 6     s0
+//  7   s0                   7   d1
+//  8   s1                   8     d0
+//                           9       s0
+//                          10     d1
+//                          11       s0
+//                          12     s0
+//                          13   s0
+
+func expand(t *DirTree, indices ...int) {
+	for _, index := range indices {
+		t.array[index].Dir.ManuallyExpanded = true
+	}
+}
+
+func TestVisibleSteps(t *testing.T) {
+	tests := []struct {
+		name     string
+		tree     DirTree
+		expanded []int
+		index    int
+		next     int
+		prev     int
+	}{
+		{"first row", testTree(2, 2, 2), nil, 0, 1, -1},
+		{"after a closed directory", testTree(2, 2, 2), nil, 7, 8, 4},
+		{"closed directory", testTree(2, 2, 2), nil, 4, 7, 1},
+		{"last row", testTree(2, 2, 2), nil, 8, -1, 7},
+		{"after an open directory", testTree(2, 2, 2), []int{4}, 7, 8, 6},
+		{"open directory", testTree(2, 2, 2), []int{4}, 4, 5, 1},
+		// the shallowest closed directory is the one displayed in place of what's inside it
+		{"after a closed directory holding an open one", testTree(2, 1, 3), []int{4}, 7, 13, 1},
+		{"after an open directory holding a closed one", testTree(2, 1, 3), []int{1, 7}, 13, -1, 12},
+		{"after a closed directory inside an open one", testTree(2, 1, 3), []int{7}, 12, 13, 10},
+		{"into an open directory inside an open one", testTree(2, 1, 3), []int{1, 2}, 2, 3, 1},
+		{"out of nested open directories", testTree(2, 1, 3), []int{1, 2, 4}, 6, 7, 5},
+	}
+
+	for _, test := range tests {
+		tree := test.tree
+		expand(&tree, test.expanded...)
+		if got := tree.nextVisible(test.index); got != test.next {
+			t.Errorf("%s: nextVisible(%d) = %d, want %d", test.name, test.index, got, test.next)
+		}
+		if got := tree.prevVisible(test.index); got != test.prev {
+			t.Errorf("%s: prevVisible(%d) = %d, want %d", test.name, test.index, got, test.prev)
+		}
+	}
+}
+
+func TestClosedRoot(t *testing.T) {
+	tree := testTree(2, 2, 2)
+	tree.array[0].Dir.ManuallyExpanded = false
+	if got := tree.nextVisible(0); got != -1 {
+		t.Errorf("nextVisible(0) = %d, want -1", got)
+	}
+	if got := tree.rowsFrom(0, 5); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("rowsFrom(0, 5) = %v, want [0]", got)
+	}
+}
+
+func TestRowsFrom(t *testing.T) {
+	tests := []struct {
+		start, count int
+		want         []int
+	}{
+		{0, 3, []int{0, 1, 4}},
+		{0, 10, []int{0, 1, 4, 7, 8}},
+		{4, 2, []int{4, 7}},
+		{8, 3, []int{8}},
+		{0, 0, []int{}},
+	}
+
+	tree := testTree(2, 2, 2)
+	for _, test := range tests {
+		if got := tree.rowsFrom(test.start, test.count); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("rowsFrom(%d, %d) = %v, want %v", test.start, test.count, got, test.want)
+		}
+	}
+}
+
+func TestVisibleWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		expanded []int
+		height   int
+		top      int
+		current  int
+		want     []int
+	}{
+		{"no room", nil, 0, 0, 0, []int{}},
+		{"negative room", nil, -2, 0, 0, []int{}},
+		{"selection at the top", nil, 3, 0, 0, []int{0, 1, 4}},
+		{"keeps the first row while the selection is shown", nil, 3, 1, 4, []int{1, 4, 7}},
+		{"scrolls one row down", nil, 3, 0, 7, []int{1, 4, 7}},
+		{"scrolls one row up", nil, 3, 4, 1, []int{1, 4, 7}},
+		{"centers a selection far away", []int{1, 4}, 3, 0, 8, []int{6, 7, 8}},
+		{"fills empty rows at the bottom", nil, 4, 7, 8, []int{1, 4, 7, 8}},
+		{"more room than rows", nil, 10, 4, 8, []int{0, 1, 4, 7, 8}},
+		{"first row inside a closed directory", nil, 3, 5, 4, []int{4, 7, 8}},
+		{"first row out of range", nil, 3, 50, 1, []int{0, 1, 4}},
+	}
+
+	for _, test := range tests {
+		tree := testTree(2, 2, 2)
+		expand(&tree, test.expanded...)
+		tree.top = test.top
+		tree.currentIndex = test.current
+		if got := tree.visibleWindow(test.height); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: visibleWindow(%d) = %v, want %v", test.name, test.height, got, test.want)
+		}
+	}
+}
+
+func TestVisibleWindowEmpty(t *testing.T) {
+	tree := testTree(2, 2, 2)
+	tree.array = tree.array[:0]
+	if got := tree.visibleWindow(5); len(got) != 0 {
+		t.Errorf("got %v for an empty tree, want no rows", got)
+	}
+}
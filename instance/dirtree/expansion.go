@@ -0,0 +1,106 @@
+package dirtree
+
+import (
+	"github.com/StructsNotClasses/mim/musicarray"
+)
+
+// ExpandAll opens every directory
+// like the other bulk operations it doesn't draw, so callers draw a single time afterwards
+func (t *DirTree) ExpandAll() {
+	t.setExpandedWhere(func(index int) bool {
+		return true
+	})
+}
+
+// CollapseAll closes every directory, though those containing the selection stay displayed since they are automatically expanded
+func (t *DirTree) CollapseAll() {
+	t.setExpandedWhere(func(index int) bool {
+		return false
+	})
+}
+
+// CollapseOthers closes every directory except those containing the selection, which are kept open even once the selection leaves them
+func (t *DirTree) CollapseOthers() {
+	enclosing := make(map[int]bool)
+	if t.IsInRange(t.currentIndex) {
+		for p := t.array[t.currentIndex].ParentIndex; p != -1; p = t.array[p].ParentIndex {
+			enclosing[p] = true
+		}
+	}
+	t.setExpandedWhere(func(index int) bool {
+		return enclosing[index]
+	})
+}
+
+// ExpandToDepth opens every directory shallower than depth and closes the rest, so entries down to that depth are displayed
+func (t *DirTree) ExpandToDepth(depth int) {
+	t.setExpandedWhere(func(index int) bool {
+		return t.array[index].Depth < depth
+	})
+}
+
+// ExpandRecursive opens the directory at index along with every directory inside it, leaving the rest of the tree alone
+func (t *DirTree) ExpandRecursive(index int) bool {
+	if !t.IsInRange(index) || t.array[index].Type != musicarray.DirectoryEntry {
+		return false
+	}
+	for i := index; i < t.array[index].Dir.EndDirectoryIndex; i++ {
+		if t.array[i].Type == musicarray.DirectoryEntry {
+			t.array[i].Dir.ManuallyExpanded = true
+		}
+	}
+	t.rowsCounted = false
+	return true
+}
+
+// setExpandedWhere changes every directory, so the rows are counted again rather than adjusted for each of them
+func (t *DirTree) setExpandedWhere(expanded func(index int) bool) {
+	for i := range t.array {
+		if t.array[i].Type == musicarray.DirectoryEntry {
+			t.array[i].Dir.ManuallyExpanded = expanded(i)
+		}
+	}
+	t.rowsCounted = false
+}
+
+func (t *DirTree) setManuallyExpanded(index int, expanded bool) {
+	t.setExpansion(index, expanded, t.array[index].Dir.AutoExpanded)
+}
+
+func (t *DirTree) setAutoExpanded(index int, expanded bool) {
+	t.setExpansion(index, t.array[index].Dir.ManuallyExpanded, expanded)
+}
+
+// setExpansion sets both ways a directory can be open, adjusting the row counts by the rows inside it if that opens or closes it
+func (t *DirTree) setExpansion(index int, manual, auto bool) {
+	dir := &t.array[index].Dir
+	was := dir.Expanded()
+	dir.ManuallyExpanded, dir.AutoExpanded = manual, auto
+	if !t.rowsCounted || dir.Expanded() == was || t.visibleAncestor(index) != index {
+		return
+	}
+
+	rows := t.rowsInside(index)
+	if !dir.Expanded() {
+		rows = -rows
+	}
+	t.rowCount += rows
+	if index < t.countedTop {
+		if t.countedTop < dir.EndDirectoryIndex {
+			// the first row was inside the directory, so it isn't displayed any more
+			t.rowsCounted = false
+		} else {
+			t.rowsAbove += rows
+		}
+	}
+}
+
+// rowsInside returns how many rows the contents of the directory at index take while it is open
+func (t DirTree) rowsInside(index int) int {
+	count := 0
+	end := t.array[index].Dir.EndDirectoryIndex
+	for i := index + 1; i != -1 && i < end; i = t.nextVisible(i) {
+		count++
+	}
+	return count
+}
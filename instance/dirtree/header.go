@@ -0,0 +1,154 @@
+package dirtree
+
+import (
+	"github.com/StructsNotClasses/mim/instance/theme"
+	"github.com/StructsNotClasses/mim/textwidth"
+
+	"fmt"
+	"strings"
+)
+
+const breadcrumbSeparator = " > "
+
+// drawHeader prints the directories the selection is in on the first row, followed by how far down the tree the view is
+func (t DirTree) drawHeader(width int, scroll scrollState) {
+	position := " " + t.scrollPosition(scroll)
+	crumbs := breadcrumbs(t.enclosingNames(t.currentIndex), width-1-len(position))
+
+	attributes := t.palette.Attributes(theme.Border)
+	t.win.Move(0, 0)
+	t.win.ClearToEOL()
+	t.win.AttrOn(attributes)
+	// the last column is left empty like every other row so nothing wraps
+	t.win.MovePrint(0, 0, textwidth.Pad(crumbs, width-1-len(position))+position)
+	t.win.AttrOff(attributes)
+}
+
+// enclosingNames returns the names of the directories containing index, outermost first
+// this is the same walk up the tree that SelectEnclosing takes
+func (t DirTree) enclosingNames(index int) []string {
+	if !t.IsInRange(index) {
+		return []string{}
+	}
+	names := []string{}
+	for p := t.array[index].ParentIndex; p != -1; p = t.array[p].ParentIndex {
+		names = append([]string{t.array[p].Name}, names...)
+	}
+	return names
+}
+
+// breadcrumbs joins the names so they fit in width columns, dropping the outermost directories first since the innermost are the most useful
+func breadcrumbs(names []string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	for n := 0; n < len(names); n++ {
+		s := strings.Join(names[n:], breadcrumbSeparator)
+		if n > 0 {
+			s = textwidth.Ellipsis + breadcrumbSeparator + s
+		}
+		if textwidth.String(s) <= width {
+			return s
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return textwidth.Truncate(names[len(names)-1], width)
+}
+
+// scrollState is how many rows can be displayed in total and how many of them are above the window
+type scrollState struct {
+	total int
+	above int
+}
+
+// scrollState returns the row counts for the last draw, moving rowsAbove along with the first row rather than counting every row again
+func (t *DirTree) scrollState() scrollState {
+	if len(t.array) == 0 {
+		return scrollState{}
+	}
+	top := 0
+	if len(t.visible) > 0 {
+		top = t.visible[0]
+	}
+	if !t.rowsCounted || !t.moveCountedTop(top) {
+		t.countRows(top)
+	}
+	return scrollState{total: t.rowCount, above: t.rowsAbove}
+}
+
+// moveCountedTop walks from the previous first row to top, counting the rows passed, and returns false if top can't be reached that way
+func (t *DirTree) moveCountedTop(top int) bool {
+	i, above := t.countedTop, t.rowsAbove
+	for i != top {
+		if i == -1 {
+			return false
+		} else if i < top {
+			i = t.nextVisible(i)
+			above++
+			if i > top {
+				return false
+			}
+		} else {
+			i = t.prevVisible(i)
+			above--
+		}
+	}
+	t.countedTop, t.rowsAbove = top, above
+	return true
+}
+
+// countRows counts every row that can be displayed and those above top
+func (t *DirTree) countRows(top int) {
+	t.rowCount, t.rowsAbove = 0, 0
+	for i := 0; i != -1; i = t.nextVisible(i) {
+		if i == top {
+			t.rowsAbove = t.rowCount
+		}
+		t.rowCount++
+	}
+	t.countedTop = top
+	t.rowsCounted = true
+}
+
+// scrollPosition describes where the view is like vim does: All when every row fits, Top or Bot at either end and a percentage otherwise
+func (t DirTree) scrollPosition(scroll scrollState) string {
+	_, height, _ := t.listArea()
+	total, above := scroll.total, scroll.above
+	switch {
+	case total <= height:
+		return "All"
+	case above == 0:
+		return "Top"
+	case above+height >= total:
+		return "Bot"
+	}
+	return fmt.Sprintf("%d%%", above*100/(total-height))
+}
+
+// drawScrollbar prints a scrollbar in column x, with a thumb the size of the window relative to every row that can be displayed
+func (t DirTree) drawScrollbar(y, height, x int, scroll scrollState) {
+	total, above := scroll.total, scroll.above
+	thumbStart, thumbSize := 0, height
+	if total > height {
+		thumbSize = height * height / total
+		if thumbSize < 1 {
+			thumbSize = 1
+		}
+		thumbStart = above * (height - thumbSize) / (total - height)
+	}
+
+	attributes := t.palette.Attributes(theme.Border)
+	t.win.AttrOn(attributes)
+	for row := 0; row < height; row++ {
+		char := "|"
+		if total <= height {
+			char = " "
+		} else if row >= thumbStart && row < thumbStart+thumbSize {
+			char = "#"
+		}
+		t.win.MovePrint(y+row, x, char)
+	}
+	t.win.AttrOff(attributes)
+}
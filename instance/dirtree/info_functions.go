@@ -35,3 +35,64 @@ func (t DirTree) IsDir(index int) bool {
 func (t DirTree) IsExpanded(index int) bool {
 	return t.array[index].Type == musicarray.DirectoryEntry && t.array[index].Dir.Expanded()
 }
+
+// IndexOfPath returns the index of the first entry with the given path
+// songs in smart playlists are copies with the same path, and since smart playlists come after the library this is the song in the library when it's there
+func (t DirTree) IndexOfPath(path string) (int, bool) {
+	for i := range t.array {
+		if t.array[i].Path == path {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// IndexOfPathIn returns the index of the entry with the given path inside the directory with parentPath
+// or the first entry with the path if none of them are inside it
+func (t DirTree) IndexOfPathIn(path, parentPath string) (int, bool) {
+	for i := range t.array {
+		if t.array[i].Path == path && t.parentPath(i) == parentPath {
+			return i, true
+		}
+	}
+	return t.IndexOfPath(path)
+}
+
+// parentPath returns the path of the directory containing the entry at index, or "" for the root
+func (t DirTree) parentPath(index int) string {
+	if parent := t.array[index].ParentIndex; t.IsInRange(parent) {
+		return t.array[parent].Path
+	}
+	return ""
+}
+
+// NextSong returns the index of the first song after index in the order of the tree, or -1 if there isn't one
+// directories are passed into whether or not they are expanded, so this is the order songs play in sequentially
+func (t DirTree) NextSong(index int) int {
+	if index < -1 {
+		index = -1
+	}
+	for i := index + 1; i < len(t.array); i++ {
+		if t.array[i].Type == musicarray.SongEntry {
+			return i
+		}
+	}
+	return -1
+}
+
+// PrevSong returns the index of the last song before index in the order of the tree, or -1 if there isn't one
+func (t DirTree) PrevSong(index int) int {
+	if index > len(t.array) {
+		index = len(t.array)
+	}
+	for i := index - 1; i >= 0; i-- {
+		if t.array[i].Type == musicarray.SongEntry {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t DirTree) Array() musicarray.MusicArray {
+	return t.array
+}
@@ -0,0 +1,109 @@
+package dirtree
+
+import (
+	"github.com/StructsNotClasses/mim/musicarray"
+)
+
+// ToggleMark marks the entry if it isn't marked and unmarks it otherwise
+// the entry becomes the anchor that MarkRange marks from
+func (t *DirTree) ToggleMark(index int) {
+	if !t.IsInRange(index) {
+		return
+	}
+	t.SetMarked(index, !t.IsMarked(index))
+	t.markAnchor = t.array[index].Path
+}
+
+func (t *DirTree) SetMarked(index int, marked bool) {
+	if marked {
+		t.marked[t.array[index].Path] = true
+	} else {
+		delete(t.marked, t.array[index].Path)
+	}
+}
+
+func (t DirTree) IsMarked(index int) bool {
+	return t.marked[t.array[index].Path]
+}
+
+// MarkRange marks every displayed entry between the last entry whose mark was toggled and index, or just index if no mark was toggled yet
+// entries hidden inside closed directories are left alone since the directory stands for them
+func (t *DirTree) MarkRange(index int) int {
+	if !t.IsInRange(index) {
+		return 0
+	}
+	anchor, ok := t.IndexOfPath(t.markAnchor)
+	if !ok {
+		anchor = index
+	}
+	start, end := t.visibleAncestor(anchor), t.visibleAncestor(index)
+	if start > end {
+		start, end = end, start
+	}
+
+	count := 0
+	for i := start; i != -1 && i <= end; i = t.nextVisible(i) {
+		t.SetMarked(i, true)
+		count++
+	}
+	return count
+}
+
+// MarkMatches marks every entry matching the current search and returns how many there are
+func (t *DirTree) MarkMatches() int {
+	count := 0
+	for i := range t.array {
+		if t.isMatch(i) {
+			t.SetMarked(i, true)
+			count++
+		}
+	}
+	return count
+}
+
+func (t *DirTree) ClearMarks() {
+	t.marked = make(map[string]bool)
+	t.markAnchor = ""
+}
+
+// Marked returns the indices of the marked entries in the order they appear in the tree
+func (t DirTree) Marked() []int {
+	indices := []int{}
+	for i := range t.array {
+		if t.IsMarked(i) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// MarkedSongs returns the marked songs along with every song inside marked directories, each only once and in the order they appear in the tree
+func (t DirTree) MarkedSongs() []int {
+	indices := []int{}
+	end := -1
+	for i := range t.array {
+		if i < end {
+			// inside a marked directory
+			if t.array[i].Type == musicarray.SongEntry {
+				indices = append(indices, i)
+			}
+			continue
+		}
+		if !t.IsMarked(i) {
+			continue
+		}
+		if t.array[i].Type == musicarray.DirectoryEntry {
+			end = t.array[i].Dir.EndDirectoryIndex
+		} else {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// SetExpanded opens or closes a directory, doing nothing for songs
+func (t *DirTree) SetExpanded(index int, expanded bool) {
+	if t.IsInRange(index) && t.array[index].Type == musicarray.DirectoryEntry {
+		t.setManuallyExpanded(index, expanded)
+	}
+}
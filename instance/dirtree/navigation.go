@@ -0,0 +1,190 @@
+package dirtree
+
+import (
+	"github.com/StructsNotClasses/mim/musicarray"
+
+	"strings"
+	"unicode"
+)
+
+// SelectDownBy moves the selection down count rows, stopping at the bottom of the tree
+// like the rest of the navigation methods it returns whether the selection moved
+func (t *DirTree) SelectDownBy(count int) bool {
+	start := t.currentIndex
+	for ; count > 0; count-- {
+		t.SelectDown()
+	}
+	return t.currentIndex != start
+}
+
+func (t *DirTree) SelectUpBy(count int) bool {
+	start := t.currentIndex
+	for ; count > 0; count-- {
+		t.SelectUp()
+	}
+	return t.currentIndex != start
+}
+
+// PageDown moves the selection and the view down by count windows of rows
+func (t *DirTree) PageDown(count int) bool {
+	return t.scrollWithSelection(count * t.pageRows())
+}
+
+func (t *DirTree) PageUp(count int) bool {
+	return t.scrollWithSelection(-count * t.pageRows())
+}
+
+// HalfPageDown moves the selection and the view down by count halves of the window
+func (t *DirTree) HalfPageDown(count int) bool {
+	return t.scrollWithSelection(count * t.halfPageRows())
+}
+
+func (t *DirTree) HalfPageUp(count int) bool {
+	return t.scrollWithSelection(-count * t.halfPageRows())
+}
+
+// pageRows leaves a row of the previous page in view so it's easier to follow where the view went
+func (t DirTree) pageRows() int {
+	_, height, _ := t.listArea()
+	if height > 1 {
+		return height - 1
+	}
+	return 1
+}
+
+func (t DirTree) halfPageRows() int {
+	_, height, _ := t.listArea()
+	if height > 1 {
+		return height / 2
+	}
+	return 1
+}
+
+// scrollWithSelection moves the selection by rows and the view along with it, so the selection stays on the same row of the window where possible
+func (t *DirTree) scrollWithSelection(rows int) bool {
+	var moved bool
+	if rows > 0 {
+		moved = t.SelectDownBy(rows)
+	} else {
+		moved = t.SelectUpBy(-rows)
+	}
+	t.Scroll(rows)
+	return moved
+}
+
+func (t *DirTree) SelectTop() bool {
+	if len(t.array) == 0 || t.currentIndex == 0 {
+		return false
+	}
+	t.Select(0)
+	return true
+}
+
+// SelectBottom selects the last entry displayed rather than the last in the array, which could be inside a closed directory
+func (t *DirTree) SelectBottom() bool {
+	if len(t.array) == 0 {
+		return false
+	}
+	last := t.visibleAncestor(len(t.array) - 1)
+	if last == t.currentIndex {
+		return false
+	}
+	t.Select(last)
+	return true
+}
+
+// NextSiblingDir selects the directory after the selected one at the same depth
+// when a song is selected the directory after the one containing it is used, since songs come after every directory they are next to
+func (t *DirTree) NextSiblingDir(count int) bool {
+	moved := false
+	for ; count > 0; count-- {
+		dir := t.directoryOf(t.currentIndex)
+		if dir == -1 {
+			break
+		}
+		next := t.array[dir].Dir.EndDirectoryIndex
+		if next >= len(t.array) || t.array[next].Type != musicarray.DirectoryEntry || t.array[next].Depth != t.array[dir].Depth {
+			break
+		}
+		t.Select(next)
+		moved = true
+	}
+	return moved
+}
+
+// PrevSiblingDir selects the directory before the selected one at the same depth, or before the one containing the selected song
+func (t *DirTree) PrevSiblingDir(count int) bool {
+	moved := false
+	for ; count > 0; count-- {
+		dir := t.directoryOf(t.currentIndex)
+		if dir == -1 || t.array[dir].Dir.PrevDirectoryIndex == -1 {
+			break
+		}
+		t.Select(t.array[dir].Dir.PrevDirectoryIndex)
+		moved = true
+	}
+	return moved
+}
+
+// directoryOf returns index if it's a directory or otherwise the directory containing it
+func (t DirTree) directoryOf(index int) int {
+	if !t.IsInRange(index) {
+		return -1
+	}
+	if t.array[index].Type == musicarray.DirectoryEntry {
+		return index
+	}
+	return t.array[index].ParentIndex
+}
+
+// FirstChild selects the first entry inside the selected directory, which opens it
+func (t *DirTree) FirstChild() bool {
+	if !t.hasChildren(t.currentIndex) {
+		return false
+	}
+	t.Select(t.currentIndex + 1)
+	return true
+}
+
+// LastChild selects the last entry directly inside the selected directory, which opens it
+func (t *DirTree) LastChild() bool {
+	if !t.hasChildren(t.currentIndex) {
+		return false
+	}
+	last := t.currentIndex + 1
+	for i := last; i < t.array[t.currentIndex].Dir.EndDirectoryIndex; {
+		last = i
+		if t.array[i].Type == musicarray.DirectoryEntry {
+			i = t.array[i].Dir.EndDirectoryIndex
+		} else {
+			i++
+		}
+	}
+	t.Select(last)
+	return true
+}
+
+func (t DirTree) hasChildren(index int) bool {
+	return t.IsInRange(index) && t.array[index].Type == musicarray.DirectoryEntry && t.array[index].Dir.EndDirectoryIndex > index+1
+}
+
+// JumpToLetter selects the next displayed entry whose name starts with the letter, ignoring case and wrapping around to the top
+func (t *DirTree) JumpToLetter(letter rune) bool {
+	if len(t.array) == 0 {
+		return false
+	}
+	letter = unicode.ToLower(letter)
+	for i := t.nextVisible(t.currentIndex); ; i = t.nextVisible(i) {
+		if i == -1 {
+			i = 0
+		}
+		if i == t.currentIndex {
+			return false
+		}
+		name := []rune(strings.TrimSpace(t.array[i].Name))
+		if len(name) > 0 && unicode.ToLower(name[0]) == letter {
+			t.Select(i)
+			return true
+		}
+	}
+}
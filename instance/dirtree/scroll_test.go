@@ -0,0 +1,112 @@
+package dirtree
+
+import (
+	"github.com/StructsNotClasses/mim/musicarray"
+
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// testTree builds a tree of directories, each holding subdirs directories down to depth levels followed by songs songs
+// nothing is expanded, and the tree has no window so only what doesn't draw can be used
+func testTree(subdirs, songs, depth int) DirTree {
+	var contents func(path string, level int) musicarray.MusicArray
+	contents = func(path string, level int) musicarray.MusicArray {
+		arr := musicarray.MusicArray{}
+		if level < depth {
+			for n := 0; n < subdirs; n++ {
+				dirPath := fmt.Sprintf("%s/d%d", path, n)
+				arr = append(arr, musicarray.VirtualDirectory(fmt.Sprintf("d%d", n), dirPath, level, contents(dirPath, level+1))...)
+			}
+		}
+		for n := 0; n < songs; n++ {
+			arr = append(arr, musicarray.Entry{
+				Type:  musicarray.SongEntry,
+				Name:  fmt.Sprintf("s%d", n),
+				Path:  fmt.Sprintf("%s/s%d", path, n),
+				Depth: level,
+			})
+		}
+		return arr
+	}
+
+	root := musicarray.MusicArray{{Type: musicarray.DirectoryEntry, Name: "root", Path: "/root", Dir: musicarray.Directory{PrevDirectoryIndex: -1}}}
+	arr := root.AppendToRoot(contents("/root", 1))
+	t := New(nil, arr, nil)
+	t.array[0].Dir.ManuallyExpanded = true
+	return t
+}
+
+// countedScrollState counts the rows from scratch, as scrollState did before the counts were kept
+func countedScrollState(t *DirTree) scrollState {
+	total, above := 0, 0
+	for i := 0; i != -1; i = t.nextVisible(i) {
+		if i == t.visible[0] {
+			above = total
+		}
+		total++
+	}
+	return scrollState{total: total, above: above}
+}
+
+func TestScrollStateFollowsChanges(t *testing.T) {
+	tree := testTree(3, 4, 3)
+	random := rand.New(rand.NewSource(1))
+	directories := []int{}
+	for i, e := range tree.array {
+		if e.Type == musicarray.DirectoryEntry {
+			directories = append(directories, i)
+		}
+	}
+
+	for step := 0; step < 2000; step++ {
+		var action string
+		switch random.Intn(6) {
+		case 0:
+			index := directories[random.Intn(len(directories))]
+			tree.Toggle(index)
+			action = fmt.Sprintf("toggle %d", index)
+		case 1:
+			index := directories[random.Intn(len(directories))]
+			tree.SetExpanded(index, random.Intn(2) == 0)
+			action = fmt.Sprintf("set expanded %d", index)
+		case 2:
+			// Scroll needs the window, so the first row is moved directly and visibleWindow keeps it if the selection is still shown
+			tree.top = random.Intn(len(tree.array))
+			action = fmt.Sprintf("top %d", tree.top)
+		case 3:
+			if random.Intn(20) == 0 {
+				tree.CollapseAll()
+				action = "collapse all"
+			} else {
+				tree.ExpandRecursive(directories[random.Intn(len(directories))])
+				action = "expand recursive"
+			}
+		default:
+			index := random.Intn(len(tree.array))
+			tree.Select(index)
+			action = fmt.Sprintf("select %d", index)
+		}
+
+		tree.visible = tree.visibleWindow(10)
+		got := tree.scrollState()
+		if want := countedScrollState(&tree); got != want {
+			t.Fatalf("step %d (%s): got %+v, want %+v", step, action, got, want)
+		}
+	}
+}
+
+func TestScrollStateAfterSetArray(t *testing.T) {
+	tree := testTree(2, 3, 2)
+	tree.visible = tree.visibleWindow(5)
+	tree.scrollState()
+
+	other := testTree(4, 2, 2)
+	other.ExpandAll()
+	tree.SetArray(other.array)
+	tree.visible = tree.visibleWindow(5)
+	if got, want := tree.scrollState(), countedScrollState(&tree); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
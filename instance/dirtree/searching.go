@@ -6,6 +6,10 @@ func (t *DirTree) SetSearch(s string) {
 	t.currentSearch = s
 }
 
+func (t DirTree) Search() string {
+	return t.currentSearch
+}
+
 func (t DirTree) NextMatch(starting int) (int, bool) {
 	for i := starting; i < len(t.array); i++ {
 		if strings.Contains(t.array[i].Name, t.currentSearch) {
@@ -23,3 +27,8 @@ func (t DirTree) PrevMatch(starting int) (int, bool) {
 	}
 	return -1, false
 }
+
+// isMatch reports whether the entry is highlighted as a match for the current search
+func (t DirTree) isMatch(index int) bool {
+	return t.currentSearch != "" && strings.Contains(t.array[index].Name, t.currentSearch)
+}
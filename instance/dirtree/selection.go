@@ -7,26 +7,9 @@ import (
 )
 
 func (t *DirTree) SelectUp() {
-	if t.currentIndex <= 0 {
-		return
-	}
-
-	current := t.array[t.currentIndex]
-	// start at the entry one index up and search for the first entry inside only expanded directories without increasing depth
-	lowest := t.currentIndex - 1
-	maxDepth := t.array[lowest].Depth
-	for i := lowest; t.array[i].Depth >= current.Depth; i-- {
-		if t.array[i].Depth >= maxDepth {
-			continue
-		}
-		if t.array[i].Type == musicarray.DirectoryEntry {
-			maxDepth = t.array[i].Depth
-			if !t.array[i].Dir.ManuallyExpanded && !t.array[i].Dir.AutoExpanded {
-				lowest = i
-			}
-		}
+	if prev := t.prevVisible(t.currentIndex); prev != -1 {
+		t.Select(prev)
 	}
-	t.Select(lowest)
 }
 
 func (t *DirTree) SelectDown() {
@@ -46,12 +29,8 @@ func (t *DirTree) SelectDown() {
 }
 
 func (t *DirTree) SelectEnclosing(index int) {
-	targetDepth := t.array[index].Depth - 1
-	if index != 0 {
-		i := index
-		for ; t.array[i].Depth != targetDepth; i-- {
-		}
-		t.Select(i)
+	if parent := t.array[index].ParentIndex; parent != -1 {
+		t.Select(parent)
 	}
 }
 
@@ -64,21 +43,19 @@ func (t *DirTree) SelectNextMatch(s string) {
 	}
 }
 
+// the directories containing the selection are automatically expanded, and those only containing the old selection stop being
 func (t *DirTree) Select(index int) {
-	t.markAutoExpanded(false)
+	old := t.currentIndex
 	t.currentIndex = index
-	t.markAutoExpanded(true)
-}
-
-// markAutoExpanded traverses the directory tree upwards marking all directories containing the currently selected item as automatically expanded
-func (t *DirTree) markAutoExpanded(value bool) {
-	i := t.currentIndex - 1
-	depth := t.array[t.currentIndex].Depth
-	for ; i >= 0; i-- {
-		if t.array[i].Depth == depth-1 {
-			// 1 level up containing folder located
-			depth--
-			t.array[i].Dir.AutoExpanded = value
+	for i := t.array[index].ParentIndex; i != -1; i = t.array[i].ParentIndex {
+		t.setAutoExpanded(i, true)
+	}
+	if old < 0 || old >= len(t.array) {
+		return
+	}
+	for i := t.array[old].ParentIndex; i != -1; i = t.array[i].ParentIndex {
+		if index <= i || index >= t.array[i].Dir.EndDirectoryIndex {
+			t.setAutoExpanded(i, false)
 		}
 	}
 }
@@ -0,0 +1,138 @@
+package instance
+
+import (
+	"github.com/d5/tengo/v2"
+
+	"strings"
+)
+
+// evalResult is the variable an expression's value is kept in so it can be printed
+const evalResult = "__eval_result"
+
+// Evaluate runs a line of tengo in the repl's scope, returning the value if the line is an expression or undefined if it's a statement
+// the line can use the variables left by earlier lines and the variables it defines are kept for later ones
+// functions defined in the line can't be kept since they only work with the rest of the line's compiled code
+// redefining a kept variable with := assigns to it instead, since kept variables are globals of the script and couldn't be redeclared
+func (i *Instance) Evaluate(line string) (tengo.Object, error) {
+	line = i.assignKept(line)
+	compiled, err := i.compileInScope(evalResult + " := (" + line + ")")
+	isExpression := err == nil
+	if !isExpression {
+		compiled, err = i.compileInScope(line)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := compiled.Run(); err != nil {
+		return nil, err
+	}
+
+	for _, v := range compiled.GetAll() {
+		switch v.Object().(type) {
+		case *tengo.UserFunction, *tengo.CompiledFunction:
+			continue
+		}
+		// hookEvent and store are defined for every script already
+		if v.Name() != evalResult && v.Name() != "hookEvent" && v.Name() != "store" {
+			i.replScope[v.Name()] = v.Object()
+		}
+	}
+	if isExpression {
+		return compiled.Get(evalResult).Object(), nil
+	}
+	return tengo.UndefinedValue, nil
+}
+
+// assignKept turns 'name :=' into 'name =' where name is a kept variable and the declaration is a statement of the line itself rather than inside of a block
+func (i *Instance) assignKept(line string) string {
+	result := strings.Builder{}
+	depth := 0
+	statementStart := true
+	for n := 0; n < len(line); n++ {
+		c := line[n]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			end := closingQuote(line, n)
+			result.WriteString(line[n:end])
+			n = end - 1
+			statementStart = false
+			continue
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == ';' || c == '\n':
+			statementStart = depth == 0
+			result.WriteByte(c)
+			continue
+		case c == ' ' || c == '\t':
+			result.WriteByte(c)
+			continue
+		case statementStart && isIdentifierByte(c, true):
+			end := n
+			for end < len(line) && isIdentifierByte(line[end], false) {
+				end++
+			}
+			name := line[n:end]
+			rest := strings.TrimLeft(line[end:], " \t")
+			if _, kept := i.replScope[name]; kept && strings.HasPrefix(rest, ":=") {
+				result.WriteString(name + line[end:len(line)-len(rest)] + "=")
+				n = len(line) - len(rest) + 1
+			} else {
+				result.WriteString(name)
+				n = end - 1
+			}
+			statementStart = false
+			continue
+		}
+		result.WriteByte(c)
+		statementStart = false
+	}
+	return result.String()
+}
+
+// closingQuote returns the index just after the end of the string or character starting at start, or the length of s if it isn't closed
+func closingQuote(s string, start int) int {
+	quote := s[start]
+	for n := start + 1; n < len(s); n++ {
+		if s[n] == '\\' && quote != '`' {
+			n++
+		} else if s[n] == quote {
+			return n + 1
+		}
+	}
+	return len(s)
+}
+
+func isIdentifierByte(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+func (i *Instance) compileInScope(source string) (*tengo.Compiled, error) {
+	script := i.newScript([]byte(source))
+	for name, value := range i.replScope {
+		if err := script.Add(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return script.Compile()
+}
+
+// evalAndPrint evaluates the line and prints its value, or the error if it couldn't be evaluated
+func (i *Instance) evalAndPrint(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	defer i.terminal.InfoPrintRuntimeError()
+	result, err := i.Evaluate(line)
+	if err != nil {
+		i.terminal.ErrorPrintln(err)
+	} else if result != tengo.UndefinedValue {
+		i.terminal.InfoPrintln(result.String())
+	}
+}
+
+func (i *Instance) ClearReplScope() {
+	i.replScope = make(map[string]tengo.Object)
+}
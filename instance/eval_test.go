@@ -0,0 +1,80 @@
+package instance
+
+import (
+	"github.com/d5/tengo/v2"
+
+	"testing"
+)
+
+func TestAssignKept(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"x := 1", "x = 1"},
+		{"x:=1", "x=1"},
+		{"x\t:= 1", "x\t= 1"},
+		{"  x := 1", "  x = 1"},
+		{"count := count + 1", "count = count + 1"},
+		{"x := 1; count := 2", "x = 1; count = 2"},
+		{"x := 1\ncount := 2", "x = 1\ncount = 2"},
+		// names that aren't kept are declared as usual
+		{"y := 1", "y := 1"},
+		{"xy := 1", "xy := 1"},
+		{"x2 := 1", "x2 := 1"},
+		{"x, y := 1, 2", "x, y := 1, 2"},
+		// only declarations are changed
+		{"x = 1", "x = 1"},
+		{"x == 1", "x == 1"},
+		{"x += 1", "x += 1"},
+		// declarations inside a block or function belong to it rather than the line
+		{"if true { x := 2 }", "if true { x := 2 }"},
+		{"f := func() { x := 2 }", "f := func() { x := 2 }"},
+		{"for i := 0; i < 3; i++ { x := i }", "for i := 0; i < 3; i++ { x := i }"},
+		{"a := [x := 1]", "a := [x := 1]"},
+		{"if true { y := 1 }; x := 2", "if true { y := 1 }; x = 2"},
+		{"if x := 1; x > 0 {}", "if x := 1; x > 0 {}"},
+		{"a.x := 1", "a.x := 1"},
+		// nor in strings and characters
+		{`s := "x := 1"`, `s := "x := 1"`},
+		{"s := `x := 1`", "s := `x := 1`"},
+		{`s := "a\"; x := 1"`, `s := "a\"; x := 1"`},
+		{`c := ';'; x := 1`, `c := ';'; x = 1`},
+		{"s := `a\\`; x := 1", "s := `a\\`; x = 1"},
+		{`s := "x := 1`, `s := "x := 1`},
+		{"", ""},
+	}
+
+	i := &Instance{replScope: map[string]tengo.Object{
+		"x":     &tengo.Int{Value: 1},
+		"count": &tengo.Int{Value: 2},
+	}}
+	for _, test := range tests {
+		if got := i.assignKept(test.line); got != test.want {
+			t.Errorf("assignKept(%q) = %q, want %q", test.line, got, test.want)
+		}
+	}
+}
+
+func TestClosingQuote(t *testing.T) {
+	tests := []struct {
+		s     string
+		start int
+		want  int
+	}{
+		{`"abc" x`, 0, 5},
+		{`x "" x`, 2, 4},
+		{`"a\"b" x`, 0, 6},
+		{`"a\\" x`, 0, 5},
+		{"`a\\` x", 0, 4},
+		{`'\'' x`, 0, 4},
+		{`"open`, 0, 5},
+		{`"ends in \`, 0, 10},
+	}
+
+	for _, test := range tests {
+		if got := closingQuote(test.s, test.start); got != test.want {
+			t.Errorf("closingQuote(%q, %d) = %d, want %d", test.s, test.start, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package instance
+
+import (
+	"github.com/StructsNotClasses/mim/script"
+
+	"github.com/d5/tengo/v2"
+
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// the events scripts can be attached to with :on
+const (
+	hookSongStart        = "song_start"
+	hookSongEnd          = "song_end"
+	hookSelectionChanged = "selection_changed"
+	hookStartup          = "startup"
+	hookShutdown         = "shutdown"
+	hookSearchChanged    = "search_changed"
+	hookLibraryRescanned = "library_rescanned"
+	hookIdle             = "idle"
+)
+
+var hookEvents = []string{hookSongStart, hookSongEnd, hookSelectionChanged, hookStartup, hookShutdown, hookSearchChanged, hookLibraryRescanned, hookIdle}
+
+// hook is a script attached to an event
+type hook struct {
+	script script.Script
+	// idle hooks run once after this long without input, then not again until there has been input
+	idle  time.Duration
+	fired bool
+}
+
+// hooks holds the scripts attached to each event, in the order they were attached
+// along with what the events are compared against, since most of them are noticed by polling like the on_no_playback script
+type hooks struct {
+	attached map[string][]hook
+	// running holds the events whose hooks are running, so a hook causing its own event doesn't run again and recurse forever
+	// hooks for other events still run, so a song_end hook that plays a song causes song_start
+	running map[string]bool
+
+	lastInput     time.Time
+	lastSelection int
+	lastSelected  string
+	lastSearch    string
+}
+
+func newHooks() hooks {
+	return hooks{
+		attached:      make(map[string][]hook),
+		running:       make(map[string]bool),
+		lastInput:     time.Now(),
+		lastSelection: -1,
+	}
+}
+
+// parseHook checks the arguments to :on, which are the event and for idle the number of seconds
+func parseHook(args []string) (string, time.Duration, error) {
+	event := args[1]
+	known := false
+	for _, e := range hookEvents {
+		known = known || e == event
+	}
+	if !known {
+		return "", 0, errors.New(fmt.Sprintf("on: unknown event '%s'; the events are %s.", event, strings.Join(hookEvents, ", ")))
+	}
+	if event != hookIdle {
+		if len(args) != 2 {
+			return "", 0, errors.New(fmt.Sprintf("on: %s takes no arguments but recieved %d.", event, len(args)-2))
+		}
+		return event, 0, nil
+	}
+	if len(args) != 3 {
+		return "", 0, errors.New("on: idle takes the number of seconds without input before it runs.")
+	}
+	seconds, err := time.ParseDuration(args[2] + "s")
+	if err != nil || seconds <= 0 {
+		return "", 0, errors.New(fmt.Sprintf("on: '%s' is not a positive number of seconds.", args[2]))
+	}
+	return event, seconds, nil
+}
+
+// AttachHook attaches the script to the event, replacing the script with the same name if there is one
+// attaching an empty script removes the one with its name instead, the same way an empty on_no_playback script removes it
+func (i *Instance) AttachHook(event string, idle time.Duration, s script.Script) {
+	i.RemoveHook(event, s.Name())
+	if !s.IsEmpty() {
+		i.hooks.attached[event] = append(i.hooks.attached[event], hook{script: s, idle: idle})
+	}
+}
+
+func (i *Instance) RemoveHook(event, name string) bool {
+	attached := i.hooks.attached[event]
+	for n := range attached {
+		if attached[n].script.Name() == name {
+			i.hooks.attached[event] = append(attached[:n], attached[n+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ClearHooks removes every script from the event, or from every event if it's empty
+func (i *Instance) ClearHooks(event string) {
+	if event == "" {
+		i.hooks.attached = make(map[string][]hook)
+	} else {
+		delete(i.hooks.attached, event)
+	}
+}
+
+// hookDescriptions lists each attached script as the :on arguments that would attach it followed by its name
+func (i *Instance) hookDescriptions() []string {
+	descriptions := []string{}
+	for event, attached := range i.hooks.attached {
+		for _, h := range attached {
+			if event == hookIdle {
+				descriptions = append(descriptions, fmt.Sprintf("%s %g\t%s", event, h.idle.Seconds(), h.script.Name()))
+			} else {
+				descriptions = append(descriptions, fmt.Sprintf("%s\t%s", event, h.script.Name()))
+			}
+		}
+	}
+	sort.Strings(descriptions)
+	return descriptions
+}
+
+// fireHook runs each script attached to the event with the event's details in the variable 'hookEvent'
+// index and path are for the entry the event is about, or -1 and "" when there isn't one
+func (i *Instance) fireHook(event string, index int, reason string, extra map[string]tengo.Object) {
+	if i.hooks.running[event] || len(i.hooks.attached[event]) == 0 {
+		return
+	}
+	for _, h := range i.hooks.attached[event] {
+		i.runHook(event, h.script, i.eventData(event, index, reason, extra))
+	}
+}
+
+func (i *Instance) runHook(event string, s script.Script, data *tengo.ImmutableMap) {
+	i.hooks.running[event] = true
+	defer delete(i.hooks.running, event)
+	if err := s.SetVariable("hookEvent", data); err != nil {
+		i.terminal.ErrorPrintln(err)
+		return
+	}
+	i.terminal.RunScriptQuietly(s)
+}
+
+func (i *Instance) eventData(event string, index int, reason string, extra map[string]tengo.Object) *tengo.ImmutableMap {
+	path := ""
+	if i.tree.IsInRange(index) {
+		path = i.tree.Array()[index].Path
+	} else {
+		index = -1
+	}
+	data := map[string]tengo.Object{
+		"name":   &tengo.String{Value: event},
+		"index":  &tengo.Int{Value: int64(index)},
+		"path":   &tengo.String{Value: path},
+		"reason": &tengo.String{Value: reason},
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	return &tengo.ImmutableMap{Value: data}
+}
+
+// pollHooks runs the hooks for events that are noticed by comparing against the last loop: the selection or the search changing and being idle
+// each comparison is made after the hooks run so a hook changing the selection doesn't cause the event again
+func (i *Instance) pollHooks(hadInput bool) {
+	now := time.Now()
+	if hadInput {
+		i.hooks.lastInput = now
+		for n := range i.hooks.attached[hookIdle] {
+			i.hooks.attached[hookIdle][n].fired = false
+		}
+	}
+
+	if index, selected := i.selectedIndexAndPath(); index != i.hooks.lastSelection || selected != i.hooks.lastSelected {
+		i.fireHook(hookSelectionChanged, index, "select", nil)
+		i.hooks.lastSelection, i.hooks.lastSelected = i.selectedIndexAndPath()
+	}
+
+	if search := i.tree.Search(); search != i.hooks.lastSearch {
+		i.fireHook(hookSearchChanged, i.tree.CurrentIndex(), "search", map[string]tengo.Object{
+			"search": &tengo.String{Value: search},
+		})
+		i.hooks.lastSearch = i.tree.Search()
+	}
+
+	idle := now.Sub(i.hooks.lastInput)
+	for n := 0; n < len(i.hooks.attached[hookIdle]); n++ {
+		h := &i.hooks.attached[hookIdle][n]
+		if h.fired || idle < h.idle {
+			continue
+		}
+		h.fired = true
+		i.runHook(hookIdle, h.script, i.eventData(hookIdle, i.tree.CurrentIndex(), "idle", map[string]tengo.Object{
+			"seconds": &tengo.Float{Value: idle.Seconds()},
+		}))
+	}
+}
+
+// syncHooks makes the polled events start from the current state, so the state at startup isn't reported as a change
+func (i *Instance) syncHooks() {
+	i.hooks.lastSelection, i.hooks.lastSelected = i.selectedIndexAndPath()
+	i.hooks.lastSearch = i.tree.Search()
+	i.hooks.lastInput = time.Now()
+}
+
+func (i *Instance) selectedIndexAndPath() (int, string) {
+	index := i.tree.CurrentIndex()
+	if !i.tree.IsInRange(index) {
+		return -1, ""
+	}
+	return index, i.tree.Array()[index].Path
+}
@@ -1,26 +1,62 @@
 package instance
 
 import (
+	gnc "github.com/rthornton128/goncurses"
+
 	"fmt"
+	"unicode/utf8"
 )
 
 func (i Instance) GetCharBlocking() rune {
 	i.bg.Timeout(-1)
-	return rune(i.bg.GetChar())
+	return i.decodeInput(i.bg.GetChar())
 }
 
 func (i Instance) GetCharNonBlocking() rune {
 	i.bg.Timeout(0)
-	return rune(i.bg.GetChar())
+	return i.decodeInput(i.bg.GetChar())
 }
 
 func (i Instance) GetLineBlocking() string {
-	i.bg.Timeout(-1)
 	line := ""
-	ch := i.bg.GetChar()
-	for ; ch != '\n'; ch = i.bg.GetChar() {
-		line = fmt.Sprintf("%s%c", line, rune(ch))
+	ch := i.GetCharBlocking()
+	for ; ch != '\n'; ch = i.GetCharBlocking() {
+		line = fmt.Sprintf("%s%c", line, ch)
 	}
 
 	return line
 }
+
+// decodeInput reads the rest of a multibyte character when first is the leading byte of one
+// ncurses returns typed characters such as accents or CJK a byte at a time
+// this changes the input timeout, so callers set their own before every read
+func (i Instance) decodeInput(first gnc.Key) rune {
+	var length int
+	switch {
+	case first >= 0xc0 && first < 0xe0:
+		length = 2
+	case first >= 0xe0 && first < 0xf0:
+		length = 3
+	case first >= 0xf0 && first < 0xf8:
+		length = 4
+	default:
+		return rune(first)
+	}
+
+	// the remaining bytes arrive together with the first, so only wait briefly for them
+	i.bg.Timeout(50)
+	bs := []byte{byte(first)}
+	for len(bs) < length {
+		next := i.bg.GetChar()
+		if next < 0x80 || next >= 0xc0 {
+			// the sequence was cut short, so the byte is the start of the next character and is left for the next read
+			if next != 0 {
+				gnc.UnGetChar(gnc.Char(next))
+			}
+			break
+		}
+		bs = append(bs, byte(next))
+	}
+	r, _ := utf8.DecodeRune(bs)
+	return r
+}
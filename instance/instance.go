@@ -2,18 +2,25 @@ package instance
 
 import (
 	"github.com/StructsNotClasses/mim/instance/dirtree"
+	"github.com/StructsNotClasses/mim/instance/layout"
 	"github.com/StructsNotClasses/mim/instance/playback"
+	"github.com/StructsNotClasses/mim/instance/statusbar"
 	"github.com/StructsNotClasses/mim/instance/terminal"
+	"github.com/StructsNotClasses/mim/instance/theme"
 	"github.com/StructsNotClasses/mim/musicarray"
+	"github.com/StructsNotClasses/mim/query"
 	"github.com/StructsNotClasses/mim/remote"
+	"github.com/StructsNotClasses/mim/titleformat"
 	"github.com/StructsNotClasses/mim/windowwriter"
 
+	"github.com/d5/tengo/v2"
 	gnc "github.com/rthornton128/goncurses"
 
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"os"
 	"time"
 )
 
@@ -22,14 +29,69 @@ type MplayerPlayer struct {
 	currentRemote  remote.Remote
 	playbackState  playback.PlaybackState
 	notifier       chan playback.Notification
-	mpOutputWindow *gnc.Window
+	mpOutput       *windowwriter.WindowWriter
+	// progress is read from mplayer's answers, which are asked for every progressInterval while playing
+	progress       *playback.Progress
+	lastQuery      time.Time
+}
+
+// Windows holds every pane so they can be rearranged when the terminal is resized
+type Windows struct {
+	Background *gnc.Window
+	Info       *gnc.Window
+	Tree       *gnc.Window
+	Input      *gnc.Window
+	Output     *gnc.Window
+	Status     *gnc.Window
 }
 
 type Instance struct {
 	bg *gnc.Window
+	windows          Windows
+	resized          chan os.Signal
+	layout           layout.Config
+	palette          *theme.Palette
 	tree             dirtree.DirTree
 	terminal         terminal.Terminal
+	status           statusbar.StatusBar
 	mp               MplayerPlayer
+	queue            []string
+	// followPlayback moves the selection to each song as it starts playing
+	followPlayback   bool
+	mouse            bool
+	// panes is where each pane was last placed, for finding which one was clicked
+	panes            layout.Layout
+	hooks            hooks
+	// commandDepth counts the commands being run by scripts, which can run scripts of their own
+	commandDepth     int
+	// exitRequested is set when a script runs :exit, since the script can't end the loop itself
+	exitRequested    bool
+	// replScope holds the variables defined by :eval and lines entered in the repl
+	replScope        map[string]tengo.Object
+	// store holds values scripts share with each other, which are saved to storeFile if one is set
+	store            map[string]interface{}
+	storeFile        string
+
+	musicDirectory string
+	library        musicarray.MusicArray
+	naming         musicarray.NamePipeline
+	smartPlaylists []SmartPlaylist
+	statsFile      string
+	// named marks hold the path of an entry so it can be selected again by name, like marks in vim
+	// they are separate from the marks in the tree used to act on several entries at once
+	namedMarks     map[string]string
+	marksFile      string
+	view           string
+	// filter hides the songs not matching it when it isn't nil
+	filter         *query.Query
+	// sortOrder applies to every directory except those in directorySorts, which is keyed by path
+	sortOrder      musicarray.SortOrder
+	directorySorts map[string]musicarray.SortOrder
+	views          map[string][]string
+
+	// templates for the status bar and exported playlists; the tree holds its own
+	statusTemplate   titleformat.Template
+	playlistTemplate titleformat.Template
 }
 
 func New(scr *gnc.Window, musicDirectory string) (Instance, error) {
@@ -40,7 +102,8 @@ func New(scr *gnc.Window, musicDirectory string) (Instance, error) {
 	scr.Timeout(0)
 
 	// create the array for the music tree
-	arr, err := musicarray.New(musicDirectory)
+	naming := musicarray.DefaultNaming()
+	arr, err := musicarray.New(musicDirectory, naming)
 	if err != nil {
 		return Instance{}, err
 	}
@@ -51,23 +114,51 @@ func New(scr *gnc.Window, musicDirectory string) (Instance, error) {
 		return Instance{}, errors.New(fmt.Sprintf("mim currently does not support playback of more than %d songs and directories at a time.", int32Max))
 	}
 
+	// colors need to be started before anything is drawn
+	palette := theme.NewPalette()
+
     // create windows
-	var bgwin, mpwin, treewin, inwin, outwin *gnc.Window
-	bgwin, mpwin, treewin, inwin, outwin, err = CreateWindows(scr)
+	windows, err := CreateWindows(scr, palette)
 	if err != nil {
 		return Instance{}, err
 	}
 
-	return Instance{
-		bg: bgwin,
-		tree:             dirtree.New(treewin, arr),
-		terminal:         terminal.New(inwin, outwin),
+	instance := Instance{
+		bg: windows.Background,
+		windows:          windows,
+		resized:          watchResizes(),
+		layout:           layout.Default(),
+		palette:          palette,
+		tree:             dirtree.New(windows.Tree, arr, palette),
+		terminal:         terminal.New(windows.Input, windows.Output, palette),
+		status:           statusbar.New(windows.Status, palette),
 		mp: MplayerPlayer{
 			currentRemote:  remote.Remote{},
 			notifier:       make(chan playback.Notification),
-			mpOutputWindow: mpwin,
+			mpOutput:       windowwriter.New(windows.Info),
+			progress:       &playback.Progress{},
 		},
-	}, nil
+		queue: []string{},
+		followPlayback: true,
+		hooks:          newHooks(),
+		replScope:      make(map[string]tengo.Object),
+		store:          make(map[string]interface{}),
+		musicDirectory: musicDirectory,
+		library:        arr,
+		naming:         naming,
+		smartPlaylists: []SmartPlaylist{},
+		namedMarks:     make(map[string]string),
+		view:           filesystemView,
+		sortOrder:      musicarray.SortOrder{Mode: musicarray.SortLexical},
+		directorySorts: make(map[string]musicarray.SortOrder),
+		views:          copyViews(builtinViews),
+		statusTemplate:   titleformat.MustParse(defaultStatusTemplate),
+		playlistTemplate: titleformat.MustParse(defaultPlaylistTemplate),
+	}
+	instance.panes, _ = instance.layout.Compute(scr.MaxYX())
+	instance.SetMouse(true)
+	instance.status.Draw()
+	return instance, nil
 }
 
 func (instance *Instance) PassFileToInput(filename string) (bool, error) {
@@ -84,24 +175,50 @@ func (instance *Instance) PassFileToInput(filename string) (bool, error) {
 	return false, nil
 }
 
-func (i *Instance) PlayIndex(index int) error {
-	i.mp.StopPlayback()
+// PlayIndex plays the song at index, stopping the one playing
+// reason is passed on to the song_start hooks, and is one of queue, script or mouse
+func (i *Instance) PlayIndex(index int, reason string) error {
+	if i.mp.playbackState.PlaybackInProgress {
+		i.mp.StopPlayback()
+		i.fireHook(hookSongEnd, i.tree.PlayingIndex(), "stopped", nil)
+	}
 	if !i.tree.IsInRange(index) {
 		return errors.New(fmt.Sprintf("instance.PlayIndex: index out of range %v.", index))
 	}
 	if i.tree.IsDir(index) {
 		return errors.New(fmt.Sprintf("instance.PlayIndex: directories cannot be played"))
 	}
-	i.tree.Select(index)
+	if i.followPlayback {
+		i.tree.Select(index)
+	}
+	i.tree.SetPlaying(index)
 	i.tree.Draw()
+	i.status.SetPlaying(i.formatEntry(i.statusTemplate, index), true)
+	entry := i.tree.Array()[index]
+	i.recordPlay(entry)
 
-	i.mp.currentRemote = playFileWithMplayer(i.tree.CurrentEntry().Path, i.mp.notifier, windowwriter.New(i.mp.mpOutputWindow))
+	i.mp.progress.Reset(entry.Song.Duration)
+	i.mp.currentRemote = playFileWithMplayer(entry.Path, i.mp.notifier, i.mp.progress.Filter(i.mp.mpOutput))
 
 	//wait for the above function to send a signal that playback began
 	i.mp.playbackState.ReceiveBlocking(i.mp.notifier)
+	// the length from the tags is only a guess until mplayer answers
+	i.mp.currentRemote.TrySendString("pausing_keep_force get_time_length\n")
+	i.fireHook(hookSongStart, index, reason, nil)
 	return nil
 }
 
+// JumpToPlaying selects the song being played, opening the directories it is in
+func (i *Instance) JumpToPlaying() bool {
+	index := i.tree.PlayingIndex()
+	if index == -1 {
+		return false
+	}
+	i.tree.Select(index)
+	i.tree.Draw()
+	return true
+}
+
 func (mp *MplayerPlayer) StopPlayback() {
 	if mp.playbackState.PlaybackInProgress {
 		mp.currentRemote.SendString("quit\n")
@@ -109,51 +226,65 @@ func (mp *MplayerPlayer) StopPlayback() {
 	}
 }
 
-func CreateWindows(scr *gnc.Window) (backgroundWindow *gnc.Window, infoWindow *gnc.Window, treeWindow *gnc.Window, commandInputWindow *gnc.Window, commandOutputWindow *gnc.Window, err error) {
+func CreateWindows(scr *gnc.Window, palette *theme.Palette) (windows Windows, err error) {
 	totalHeight, totalWidth := scr.MaxYX()
-	leftToRightRatio := 2.0/3.0
-
-	var terminalWidth int = int(leftToRightRatio*float64(totalWidth)) - 2
-	var treeWidth int = totalWidth - terminalWidth - 3
-
-    var mpHeight int = (totalHeight - 4)/3
-    var inputHeight int = (totalHeight - mpHeight - 4)/2
-    var outputHeight int = totalHeight - mpHeight - inputHeight - 4
+	l, err := layout.Default().Compute(totalHeight, totalWidth)
+	if err != nil {
+		return
+	}
 
-    backgroundWindow = scr
+	windows.Background = scr
 
 	//create the window that displays information about the current song
-	infoWindow, err = gnc.NewWindow(mpHeight, terminalWidth, 1, 1)
+	windows.Info, err = gnc.NewWindow(l.Info.Height, l.Info.Width, l.Info.Y, l.Info.X)
 	if err != nil {
 		return
 	}
-	infoWindow.ScrollOk(true)
+	windows.Info.ScrollOk(true)
 
 	//create the window that holds the song tree
-	treeWindow, err = gnc.NewWindow(totalHeight-2, treeWidth, 1, terminalWidth+2)
+	windows.Tree, err = gnc.NewWindow(l.Tree.Height, l.Tree.Width, l.Tree.Y, l.Tree.X)
 	if err != nil {
 		return
 	}
 
 	//create the window that allows user input
-	commandInputWindow, err = gnc.NewWindow(inputHeight, terminalWidth, mpHeight + outputHeight + 3, 1)
+	windows.Input, err = gnc.NewWindow(l.Input.Height, l.Input.Width, l.Input.Y, l.Input.X)
 	if err != nil {
-		return 
+		return
 	}
-	commandInputWindow.ScrollOk(true)
+	windows.Input.ScrollOk(true)
 
 	//create the window that holds command output
-	commandOutputWindow, err = gnc.NewWindow(outputHeight, terminalWidth, mpHeight+2, 1)
+	windows.Output, err = gnc.NewWindow(l.Output.Height, l.Output.Width, l.Output.Y, l.Output.X)
+	if err != nil {
+		return
+	}
+	windows.Output.ScrollOk(true)
+
+	//create the window that shows what is playing
+	windows.Status, err = gnc.NewWindow(l.Status.Height, l.Status.Width, l.Status.Y, l.Status.X)
 	if err != nil {
 		return
 	}
-	commandOutputWindow.ScrollOk(true)
 
-	scr.Box('|', '-')
-    scr.VLine(1, terminalWidth+1, '|', totalHeight-2)
-	scr.HLine(mpHeight + 1, 1, '=', terminalWidth)
-	scr.HLine(mpHeight+outputHeight+2, 1, '=', terminalWidth)
+	drawBorders(scr, l, palette)
 	scr.Refresh()
 
 	return
 }
+
+func drawBorders(scr *gnc.Window, l layout.Layout, palette *theme.Palette) {
+	// the border characters carry the style themselves since ncurses ignores the window attributes when drawing lines
+	attributes := palette.Attributes(theme.Border)
+	if l.Box {
+		scr.Box('|'|attributes, '-'|attributes)
+	}
+	for _, line := range l.Lines {
+		if line.Vertical {
+			scr.VLine(line.Y, line.X, gnc.Char(line.Char)|attributes, line.Length)
+		} else {
+			scr.HLine(line.Y, line.X, gnc.Char(line.Char)|attributes, line.Length)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package layout
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Pane string
+
+const (
+	Info   Pane = "info"
+	Tree   Pane = "tree"
+	Output Pane = "output"
+	Input  Pane = "input"
+	Status Pane = "status"
+)
+
+type Side int
+
+const (
+	Right Side = iota
+	Left
+)
+
+// Config describes how the panes are arranged
+type Config struct {
+	// TreeRatio is the fraction of the width taken by the tree
+	TreeRatio float64
+	// InfoRatio is the fraction of the column's height taken by the info pane
+	InfoRatio float64
+	TreeSide  Side
+	// Order is the order of the info, output and input panes from top to bottom
+	Order   []Pane
+	Hidden  map[Pane]bool
+	Borders bool
+}
+
+// Default returns the original layout: the tree on the right third of the screen and the info, output and input panes stacked on the left, with the status bar along the bottom
+func Default() Config {
+	return Config{
+		TreeRatio: 1.0 / 3.0,
+		InfoRatio: 1.0 / 3.0,
+		TreeSide:  Right,
+		Order:     []Pane{Info, Output, Input},
+		Hidden:    make(map[Pane]bool),
+		Borders:   true,
+	}
+}
+
+// ParsePane checks that name is a pane
+func ParsePane(name string) (Pane, error) {
+	switch pane := Pane(name); pane {
+	case Info, Tree, Output, Input, Status:
+		return pane, nil
+	}
+	return "", errors.New(fmt.Sprintf("layout: '%s' is not a pane; the panes are info, tree, output, input and status.", name))
+}
+
+// Set changes one setting from its textual form, as used by the :layout command
+func (c *Config) Set(setting, value string) error {
+	switch setting {
+	case "tree_ratio", "info_ratio":
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil || ratio <= 0 || ratio >= 1 {
+			return errors.New(fmt.Sprintf("layout: %s must be a number between 0 and 1, not '%s'.", setting, value))
+		}
+		if setting == "tree_ratio" {
+			c.TreeRatio = ratio
+		} else {
+			c.InfoRatio = ratio
+		}
+	case "tree_side":
+		switch value {
+		case "left":
+			c.TreeSide = Left
+		case "right":
+			c.TreeSide = Right
+		default:
+			return errors.New(fmt.Sprintf("layout: tree_side must be left or right, not '%s'.", value))
+		}
+	case "order":
+		order := []Pane{}
+		for _, name := range strings.Split(value, ",") {
+			pane, err := ParsePane(name)
+			if err != nil {
+				return err
+			}
+			if pane == Tree {
+				return errors.New("layout: the tree is placed with tree_side rather than order.")
+			}
+			if pane == Status {
+				return errors.New("layout: the status bar is always at the bottom.")
+			}
+			order = append(order, pane)
+		}
+		if len(order) != 3 || order[0] == order[1] || order[1] == order[2] || order[0] == order[2] {
+			return errors.New("layout: order must list info, output and input once each, eg info,output,input.")
+		}
+		c.Order = order
+	case "borders":
+		switch value {
+		case "on":
+			c.Borders = true
+		case "off":
+			c.Borders = false
+		default:
+			return errors.New(fmt.Sprintf("layout: borders must be on or off, not '%s'.", value))
+		}
+	default:
+		return errors.New(fmt.Sprintf("layout: unknown setting '%s'.", setting))
+	}
+	return nil
+}
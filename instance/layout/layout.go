@@ -0,0 +1,161 @@
+package layout
+
+import (
+	"errors"
+	"fmt"
+)
+
+const MinHeight = 10
+const MinWidth = 30
+
+// Rect is the position and size of a window on the screen
+// hidden panes have a zero height and width
+type Rect struct {
+	Y, X          int
+	Height, Width int
+}
+
+func (r Rect) Hidden() bool {
+	return r.Height <= 0 || r.Width <= 0
+}
+
+// Line is a border line drawn on the background window
+type Line struct {
+	Y, X     int
+	Length   int
+	Vertical bool
+	Char     rune
+}
+
+// Layout holds the geometry of every pane along with the borders separating them
+type Layout struct {
+	Info   Rect
+	Tree   Rect
+	Input  Rect
+	Output Rect
+	Status Rect
+	Box    bool
+	Lines  []Line
+}
+
+// Compute arranges the panes for a screen of the given size
+// the info, output and input panes are stacked in a column beside the tree, which takes TreeRatio of the width, and the status bar is a single row below them all
+func (c Config) Compute(height, width int) (Layout, error) {
+	if height < MinHeight || width < MinWidth {
+		return Layout{}, tooSmall(height, width)
+	}
+
+	l := Layout{Box: c.Borders}
+
+	// with borders on there is a box around the screen and a line between each pair of panes
+	inset, divider := 0, 0
+	if c.Borders {
+		inset, divider = 1, 1
+	}
+	innerHeight := height - 2*inset
+	innerWidth := width - 2*inset
+	if !c.Hidden[Status] {
+		innerHeight -= 1 + divider
+		l.Status = Rect{Y: inset + innerHeight + divider, X: inset, Height: 1, Width: innerWidth}
+		if c.Borders {
+			l.Lines = append(l.Lines, Line{Y: inset + innerHeight, X: inset, Length: innerWidth, Char: '-'})
+		}
+	}
+
+	column := c.visibleColumn()
+	treeVisible := !c.Hidden[Tree]
+
+	treeWidth, columnWidth := 0, 0
+	switch {
+	case treeVisible && len(column) > 0:
+		treeWidth = int(c.TreeRatio*float64(innerWidth) + 0.5)
+		columnWidth = innerWidth - treeWidth - divider
+	case treeVisible:
+		treeWidth = innerWidth
+	default:
+		columnWidth = innerWidth
+	}
+	if treeVisible && treeWidth < 1 || len(column) > 0 && columnWidth < 1 {
+		return Layout{}, tooSmall(height, width)
+	}
+
+	columnX, treeX, dividerX := inset, inset+columnWidth+divider, inset+columnWidth
+	if !treeVisible || len(column) == 0 {
+		// a lone pane takes the whole screen
+		columnX, treeX = inset, inset
+	} else if c.TreeSide == Left {
+		treeX, columnX, dividerX = inset, inset+treeWidth+divider, inset+treeWidth
+	}
+	if treeVisible {
+		l.Tree = Rect{Y: inset, X: treeX, Height: innerHeight, Width: treeWidth}
+		if len(column) > 0 && c.Borders {
+			l.Lines = append(l.Lines, Line{Y: inset, X: dividerX, Length: innerHeight, Vertical: true, Char: '|'})
+		}
+	}
+
+	heights := c.columnHeights(column, innerHeight-divider*(len(column)-1))
+	y := inset
+	for n, pane := range column {
+		if heights[n] < 1 {
+			return Layout{}, tooSmall(height, width)
+		}
+		r := Rect{Y: y, X: columnX, Height: heights[n], Width: columnWidth}
+		switch pane {
+		case Info:
+			l.Info = r
+		case Output:
+			l.Output = r
+		case Input:
+			l.Input = r
+		}
+		y += heights[n]
+		if n != len(column)-1 && c.Borders {
+			l.Lines = append(l.Lines, Line{Y: y, X: columnX, Length: columnWidth, Char: '='})
+		}
+		y += divider
+	}
+
+	return l, nil
+}
+
+// visibleColumn returns the panes stacked beside the tree that aren't hidden, in order
+func (c Config) visibleColumn() []Pane {
+	column := []Pane{}
+	for _, pane := range c.Order {
+		if !c.Hidden[pane] {
+			column = append(column, pane)
+		}
+	}
+	return column
+}
+
+// columnHeights splits the available height between the panes in the column
+// the info pane takes InfoRatio of it if anything else is shown and the others share the rest evenly, with any leftover going to the first of them
+func (c Config) columnHeights(column []Pane, available int) []int {
+	heights := make([]int, len(column))
+	rest := available
+	others := len(column)
+	for n, pane := range column {
+		if pane == Info && len(column) > 1 {
+			heights[n] = int(c.InfoRatio*float64(available) + 1e-9)
+			rest -= heights[n]
+			others--
+		}
+	}
+	first := true
+	for n, pane := range column {
+		if pane == Info && len(column) > 1 {
+			continue
+		}
+		heights[n] = rest / others
+		if first {
+			heights[n] += rest % others
+			first = false
+		}
+	}
+	return heights
+}
+
+func tooSmall(height, width int) error {
+	return errors.New(fmt.Sprintf("Terminal too small: mim needs at least %dx%d but has %dx%d.", MinWidth, MinHeight, width, height))
+}
@@ -0,0 +1,164 @@
+package layout
+
+import (
+	"testing"
+)
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name          string
+		change        func(c *Config)
+		height, width int
+		want          Layout
+		lines         int
+	}{
+		{
+			"default", func(c *Config) {}, 24, 80,
+			Layout{
+				Info:   Rect{Y: 1, X: 1, Height: 6, Width: 51},
+				Output: Rect{Y: 8, X: 1, Height: 6, Width: 51},
+				Input:  Rect{Y: 15, X: 1, Height: 6, Width: 51},
+				Tree:   Rect{Y: 1, X: 53, Height: 20, Width: 26},
+				Status: Rect{Y: 22, X: 1, Height: 1, Width: 78},
+			},
+			4,
+		},
+		{
+			"no borders", func(c *Config) { c.Borders = false }, 24, 80,
+			Layout{
+				Info:   Rect{Y: 0, X: 0, Height: 7, Width: 53},
+				Output: Rect{Y: 7, X: 0, Height: 8, Width: 53},
+				Input:  Rect{Y: 15, X: 0, Height: 8, Width: 53},
+				Tree:   Rect{Y: 0, X: 53, Height: 23, Width: 27},
+				Status: Rect{Y: 23, X: 0, Height: 1, Width: 80},
+			},
+			0,
+		},
+		{
+			"tree on the left", func(c *Config) { c.TreeSide = Left }, 24, 80,
+			Layout{
+				Info:   Rect{Y: 1, X: 28, Height: 6, Width: 51},
+				Output: Rect{Y: 8, X: 28, Height: 6, Width: 51},
+				Input:  Rect{Y: 15, X: 28, Height: 6, Width: 51},
+				Tree:   Rect{Y: 1, X: 1, Height: 20, Width: 26},
+				Status: Rect{Y: 22, X: 1, Height: 1, Width: 78},
+			},
+			4,
+		},
+		{
+			// the leftover row goes to the first pane after the info pane
+			"smallest screen", func(c *Config) {}, MinHeight, MinWidth,
+			Layout{
+				Info:   Rect{Y: 1, X: 1, Height: 1, Width: 18},
+				Output: Rect{Y: 3, X: 1, Height: 2, Width: 18},
+				Input:  Rect{Y: 6, X: 1, Height: 1, Width: 18},
+				Tree:   Rect{Y: 1, X: 20, Height: 6, Width: 9},
+				Status: Rect{Y: 8, X: 1, Height: 1, Width: 28},
+			},
+			4,
+		},
+		{
+			"hidden tree", func(c *Config) { c.Hidden[Tree] = true }, 24, 80,
+			Layout{
+				Info:   Rect{Y: 1, X: 1, Height: 6, Width: 78},
+				Output: Rect{Y: 8, X: 1, Height: 6, Width: 78},
+				Input:  Rect{Y: 15, X: 1, Height: 6, Width: 78},
+				Status: Rect{Y: 22, X: 1, Height: 1, Width: 78},
+			},
+			3,
+		},
+		{
+			"hidden status bar", func(c *Config) { c.Hidden[Status] = true }, 24, 80,
+			Layout{
+				Info:   Rect{Y: 1, X: 1, Height: 6, Width: 51},
+				Output: Rect{Y: 8, X: 1, Height: 7, Width: 51},
+				Input:  Rect{Y: 16, X: 1, Height: 7, Width: 51},
+				Tree:   Rect{Y: 1, X: 53, Height: 22, Width: 26},
+			},
+			3,
+		},
+		{
+			// without anything else beside it the info pane takes the whole column
+			"only the info pane beside the tree", func(c *Config) { c.Hidden[Output], c.Hidden[Input] = true, true }, 24, 80,
+			Layout{
+				Info:   Rect{Y: 1, X: 1, Height: 20, Width: 51},
+				Tree:   Rect{Y: 1, X: 53, Height: 20, Width: 26},
+				Status: Rect{Y: 22, X: 1, Height: 1, Width: 78},
+			},
+			2,
+		},
+		{
+			"only the tree", func(c *Config) { c.Hidden[Info], c.Hidden[Output], c.Hidden[Input] = true, true, true }, 24, 80,
+			Layout{
+				Tree:   Rect{Y: 1, X: 1, Height: 20, Width: 78},
+				Status: Rect{Y: 22, X: 1, Height: 1, Width: 78},
+			},
+			1,
+		},
+		{
+			"reordered", func(c *Config) { c.Order = []Pane{Input, Output, Info} }, 24, 80,
+			Layout{
+				Input:  Rect{Y: 1, X: 1, Height: 6, Width: 51},
+				Output: Rect{Y: 8, X: 1, Height: 6, Width: 51},
+				Info:   Rect{Y: 15, X: 1, Height: 6, Width: 51},
+				Tree:   Rect{Y: 1, X: 53, Height: 20, Width: 26},
+				Status: Rect{Y: 22, X: 1, Height: 1, Width: 78},
+			},
+			4,
+		},
+	}
+
+	for _, test := range tests {
+		c := Default()
+		test.change(&c)
+		got, err := c.Compute(test.height, test.width)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		for _, pane := range []struct {
+			name      string
+			got, want Rect
+		}{
+			{"info", got.Info, test.want.Info},
+			{"output", got.Output, test.want.Output},
+			{"input", got.Input, test.want.Input},
+			{"tree", got.Tree, test.want.Tree},
+			{"status", got.Status, test.want.Status},
+		} {
+			if pane.got != pane.want {
+				t.Errorf("%s: got %+v for the %s pane, want %+v", test.name, pane.got, pane.name, pane.want)
+			}
+		}
+		if len(got.Lines) != test.lines {
+			t.Errorf("%s: got %d border lines, want %d", test.name, len(got.Lines), test.lines)
+		}
+		if got.Box != c.Borders {
+			t.Errorf("%s: got box %v, want %v", test.name, got.Box, c.Borders)
+		}
+	}
+}
+
+func TestComputeTooSmall(t *testing.T) {
+	tests := []struct {
+		name          string
+		change        func(c *Config)
+		height, width int
+	}{
+		{"too short", func(c *Config) {}, MinHeight - 1, 80},
+		{"too narrow", func(c *Config) {}, 24, MinWidth - 1},
+		{"no size", func(c *Config) {}, 0, 0},
+		{"negative size", func(c *Config) {}, -5, -5},
+		// big enough overall, but the ratios leave a pane with nothing
+		{"tree ratio rounds to nothing", func(c *Config) { c.TreeRatio = 0.01 }, MinHeight, MinWidth},
+		{"info ratio rounds to nothing", func(c *Config) { c.InfoRatio = 0.1 }, MinHeight, MinWidth},
+	}
+
+	for _, test := range tests {
+		c := Default()
+		test.change(&c)
+		if l, err := c.Compute(test.height, test.width); err == nil {
+			t.Errorf("%s: Compute(%d, %d) = %+v, expected an error", test.name, test.height, test.width, l)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package instance
+
+import (
+	"github.com/StructsNotClasses/mim/musicarray"
+	"github.com/StructsNotClasses/mim/query"
+
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const smartPlaylistDirectoryName = "Smart Playlists"
+const smartPlaylistPathPrefix = "playlist://"
+
+const filesystemView = "filesystem"
+const viewPathPrefix = "view://"
+
+// builtinViews are the tag based views available without defining any, each a list of the fields songs are nested by
+var builtinViews = map[string][]string{
+	"artist": {"albumartist", "album"},
+	"genre":  {"genre", "artist"},
+	"year":   {"year", "album"},
+}
+
+// SmartPlaylist is a virtual directory whose songs are every song in the library matching a query
+type SmartPlaylist struct {
+	Name  string
+	Query query.Query
+}
+
+// AddSmartPlaylist adds or replaces the smart playlist with the given name and rebuilds the tree
+func (i *Instance) AddSmartPlaylist(name, q string) error {
+	parsed, err := query.Parse(q)
+	if err != nil {
+		return err
+	}
+	for index := range i.smartPlaylists {
+		if i.smartPlaylists[index].Name == name {
+			i.smartPlaylists[index].Query = parsed
+			i.refreshTree()
+			return nil
+		}
+	}
+	i.smartPlaylists = append(i.smartPlaylists, SmartPlaylist{Name: name, Query: parsed})
+	i.refreshTree()
+	return nil
+}
+
+func (i *Instance) RemoveSmartPlaylist(name string) bool {
+	for index := range i.smartPlaylists {
+		if i.smartPlaylists[index].Name == name {
+			i.smartPlaylists = append(i.smartPlaylists[:index], i.smartPlaylists[index+1:]...)
+			i.refreshTree()
+			return true
+		}
+	}
+	return false
+}
+
+// Rescan reads the music directory again, keeping play counts of songs that are still present
+func (i *Instance) Rescan() error {
+	arr, err := musicarray.New(i.musicDirectory, i.naming)
+	if err != nil {
+		return err
+	}
+
+	stats := make(map[string]*musicarray.Stats)
+	for _, e := range i.library {
+		if e.Type == musicarray.SongEntry {
+			stats[e.Path] = e.Song.Stats
+		}
+	}
+	for index := range arr {
+		if s, ok := stats[arr[index].Path]; ok {
+			arr[index].Song.Stats = s
+		}
+	}
+
+	i.library = arr
+	i.refreshTree()
+	i.fireHook(hookLibraryRescanned, -1, "rescan", nil)
+	return nil
+}
+
+// SetView switches the tree between the filesystem layout and the tag based views
+func (i *Instance) SetView(name string) error {
+	if _, ok := i.views[name]; !ok && name != filesystemView {
+		return errors.New(fmt.Sprintf("view: no view named '%s'.", name))
+	}
+	i.view = name
+	i.refreshTree()
+	return nil
+}
+
+// DefineView adds or replaces a view nesting songs by the given fields
+func (i *Instance) DefineView(name string, fields []string) error {
+	if name == filesystemView {
+		return errors.New(fmt.Sprintf("define_view: '%s' can't be redefined.", filesystemView))
+	}
+	i.views[name] = fields
+	if i.view == name {
+		i.refreshTree()
+	}
+	return nil
+}
+
+// SetFilter hides every song not matching the query, along with the directories left empty, until the filter is cleared
+// smart playlists aren't filtered since they are already made by queries of their own
+func (i *Instance) SetFilter(q string) error {
+	parsed, err := query.Parse(q)
+	if err != nil {
+		return err
+	}
+	i.filter = &parsed
+	i.refreshTree()
+	return nil
+}
+
+func (i *Instance) ClearFilter() {
+	i.filter = nil
+	i.refreshTree()
+}
+
+// refreshTree rebuilds the tree from the library in the current view, evaluating the smart playlists again
+func (i *Instance) refreshTree() {
+	arr := i.library
+	if fields, ok := i.views[i.view]; ok && i.view != filesystemView {
+		arr = i.library.GroupBy(strings.Title(i.view), viewPathPrefix+i.view, fields, query.FieldValue)
+	}
+	now := time.Now()
+	if i.filter != nil {
+		arr = arr.Keep(func(e musicarray.Entry) bool {
+			return i.filter.Match(e, now)
+		})
+	}
+	// the library is sorted before the smart playlists are added so they stay after it, where IndexOfPath relies on finding the library's copy of a song first
+	sorted := i.sortOrder != (musicarray.SortOrder{Mode: musicarray.SortLexical}) || len(i.directorySorts) > 0
+	if sorted {
+		arr = arr.Sort(i.sortOrder, i.directorySorts)
+	}
+	if len(i.smartPlaylists) > 0 {
+		playlists := musicarray.MusicArray{}
+		for _, playlist := range i.smartPlaylists {
+			songs := musicarray.MusicArray{}
+			for _, index := range playlist.Query.Filter(i.library, now) {
+				if i.library[index].Type == musicarray.SongEntry {
+					// flatten the songs so they all sit directly inside the playlist
+					song := i.library[index]
+					song.Depth = 0
+					songs = append(songs, song)
+				}
+			}
+			playlists = append(playlists, musicarray.VirtualDirectory(playlist.Name, smartPlaylistPathPrefix+playlist.Name, 2, songs)...)
+		}
+		directory := musicarray.VirtualDirectory(smartPlaylistDirectoryName, smartPlaylistPathPrefix, 1, playlists)
+		if sorted {
+			directory = directory.Sort(i.sortOrder, i.directorySorts)
+		}
+		arr = arr.AppendToRoot(directory)
+	}
+	i.tree.SetArray(arr)
+	i.tree.Draw()
+}
+
+// SetSortOrder changes how the contents of directories without an order of their own are sorted
+func (i *Instance) SetSortOrder(order musicarray.SortOrder) {
+	i.sortOrder = order
+	i.refreshTree()
+}
+
+// SetDirectorySortOrder sorts the contents of the selected directory, or of the directory containing the selected song, in its own order
+// the order also applies to the directories inside it that don't have their own
+func (i *Instance) SetDirectorySortOrder(order musicarray.SortOrder) error {
+	path, err := i.selectedDirectoryPath()
+	if err != nil {
+		return err
+	}
+	i.directorySorts[path] = order
+	i.refreshTree()
+	return nil
+}
+
+// ClearDirectorySortOrder makes the selected directory use the order of the directory it is in again
+func (i *Instance) ClearDirectorySortOrder() error {
+	path, err := i.selectedDirectoryPath()
+	if err != nil {
+		return err
+	}
+	delete(i.directorySorts, path)
+	i.refreshTree()
+	return nil
+}
+
+func (i *Instance) selectedDirectoryPath() (string, error) {
+	index := i.tree.CurrentIndex()
+	if !i.tree.IsInRange(index) {
+		return "", errors.New("sort_directory: nothing is selected.")
+	}
+	arr := i.tree.Array()
+	if !i.tree.IsDir(index) {
+		index = arr[index].ParentIndex
+	}
+	return arr[index].Path, nil
+}
+
+// recordPlay increments the play count of a song and saves the counts if a stats file is in use
+func (i *Instance) recordPlay(e musicarray.Entry) {
+	if e.Song.Stats == nil {
+		return
+	}
+	e.Song.Stats.PlayCount++
+	e.Song.Stats.LastPlayed = time.Now()
+	if i.statsFile != "" {
+		if err := i.saveStats(i.statsFile); err != nil {
+			i.terminal.ErrorPrintf("stats: failed to save '%s' with error '%v'\n", i.statsFile, err)
+		}
+	}
+}
+
+// LoadStats reads play counts from a file and remembers it so counts are saved back to it after every song played
+// each line of the file is <play count> <last played unix time> <path>, separated by tabs
+func (i *Instance) LoadStats(filename string) error {
+	i.statsFile = filename
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		// the file will be created once something is played
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stats := make(map[string]*musicarray.Stats)
+	for _, e := range i.library {
+		if e.Type == musicarray.SongEntry {
+			stats[e.Path] = e.Song.Stats
+		}
+	}
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(fields) != 3 {
+			return errors.New(fmt.Sprintf("stats: line %d of '%s' is malformed.", line, filename))
+		}
+		count, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return errors.New(fmt.Sprintf("stats: line %d of '%s' has an invalid play count.", line, filename))
+		}
+		lastPlayed, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return errors.New(fmt.Sprintf("stats: line %d of '%s' has an invalid time.", line, filename))
+		}
+		if s, ok := stats[fields[2]]; ok && s != nil {
+			s.PlayCount = count
+			s.LastPlayed = time.Unix(lastPlayed, 0)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	i.refreshTree()
+	return nil
+}
+
+func (i *Instance) saveStats(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, e := range i.library {
+		if e.Type == musicarray.SongEntry && e.Song.Stats != nil && e.Song.Stats.PlayCount > 0 {
+			fmt.Fprintf(w, "%d\t%d\t%s\n", e.Song.Stats.PlayCount, e.Song.Stats.LastPlayed.Unix(), e.Path)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func copyViews(views map[string][]string) map[string][]string {
+	result := make(map[string][]string)
+	for name, fields := range views {
+		result[name] = fields
+	}
+	return result
+}
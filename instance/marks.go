@@ -0,0 +1,24 @@
+package instance
+
+// EnqueueMarked adds the marked songs, and the songs inside marked directories, to the queue and returns how many were added
+func (i *Instance) EnqueueMarked() int {
+	songs := i.tree.MarkedSongs()
+	for _, index := range songs {
+		i.Enqueue(index)
+	}
+	return len(songs)
+}
+
+// ExportMarked writes the marked songs, and the songs inside marked directories, to an extended m3u playlist
+func (i *Instance) ExportMarked(filename string) (int, error) {
+	songs := i.tree.MarkedSongs()
+	return len(songs), i.writePlaylist(filename, songs)
+}
+
+// ExpandMarked opens or closes every marked directory
+func (i *Instance) ExpandMarked(expanded bool) {
+	for _, index := range i.tree.Marked() {
+		i.tree.SetExpanded(index, expanded)
+	}
+	i.tree.Draw()
+}
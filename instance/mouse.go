@@ -0,0 +1,100 @@
+package instance
+
+import (
+	"github.com/StructsNotClasses/mim/instance/layout"
+
+	gnc "github.com/rthornton128/goncurses"
+
+	"fmt"
+	"time"
+)
+
+// goncurses has no constant for the wheel turning down, which ncurses reports as button 5 being pressed
+const mouseWheelDown gnc.MouseButton = 0x200000
+
+// the events mim uses: clicks, double clicks and the wheel
+const mouseEvents = gnc.M_B1_CLICKED | gnc.M_B1_DBL_CLICKED | gnc.M_B4_PRESSED | mouseWheelDown
+
+// wheelRows is how many rows the tree scrolls for each turn of the wheel
+const wheelRows = 3
+
+// progressInterval is how often mplayer is asked how far into the song it is
+const progressInterval = 500 * time.Millisecond
+
+// SetMouse turns mouse support on or off
+// while it is on the terminal can't select text with the mouse, which is why it can be turned off
+func (i *Instance) SetMouse(enabled bool) {
+	i.mouse = enabled
+	if enabled {
+		gnc.MouseMask(mouseEvents, nil)
+	} else {
+		gnc.MouseMask(0, nil)
+	}
+}
+
+// handleMouse routes a mouse event to the pane it happened in
+// clicking a tree row selects it and double clicking plays the song or opens or closes the directory, the wheel scrolls the tree and clicking the progress bar seeks
+func (i *Instance) handleMouse() {
+	event := gnc.GetMouse()
+	if event == nil {
+		return
+	}
+
+	switch {
+	case contains(i.panes.Tree, event.Y, event.X):
+		i.treeMouse(event.Y-i.panes.Tree.Y, event.State)
+	case contains(i.panes.Status, event.Y, event.X):
+		if event.State&(gnc.M_B1_CLICKED|gnc.M_B1_DBL_CLICKED) != 0 {
+			i.seekTo(event.X - i.panes.Status.X)
+		}
+	}
+}
+
+func (i *Instance) treeMouse(row int, state gnc.MouseButton) {
+	switch {
+	case state&gnc.M_B4_PRESSED != 0:
+		i.tree.Scroll(-wheelRows)
+	case state&mouseWheelDown != 0:
+		i.tree.Scroll(wheelRows)
+	case state&(gnc.M_B1_CLICKED|gnc.M_B1_DBL_CLICKED) != 0:
+		index, ok := i.tree.RowIndex(row)
+		if !ok {
+			return
+		}
+		i.tree.Select(index)
+		if state&gnc.M_B1_DBL_CLICKED != 0 {
+			if i.tree.IsDir(index) {
+				i.tree.Toggle(index)
+			} else if err := i.PlayIndex(index, "mouse"); err != nil {
+				i.terminal.ErrorPrintln(err)
+			}
+		}
+	}
+	i.tree.Draw()
+}
+
+// seekTo moves playback to the part of the song that column x of the status bar's progress bar stands for
+func (i *Instance) seekTo(x int) {
+	fraction, ok := i.status.SeekFraction(x)
+	if !ok || !i.mp.playbackState.PlaybackInProgress {
+		return
+	}
+	// seek type 1 is a percentage of the song
+	i.mp.currentRemote.TrySendString(fmt.Sprintf("seek %.2f 1\n", fraction*100))
+	i.mp.lastQuery = time.Time{}
+}
+
+// updateProgress asks mplayer where playback is every progressInterval and shows the last answer in the status bar
+func (i *Instance) updateProgress() {
+	if !i.mp.playbackState.PlaybackInProgress || time.Since(i.mp.lastQuery) < progressInterval {
+		return
+	}
+	i.mp.lastQuery = time.Now()
+	// pausing_keep_force stops the query from unpausing a paused song
+	i.mp.currentRemote.TrySendString("pausing_keep_force get_time_pos\n")
+	i.status.SetProgress(i.mp.progress.Get())
+}
+
+func contains(r layout.Rect, y, x int) bool {
+	return !r.Hidden() && y >= r.Y && y < r.Y+r.Height && x >= r.X && x < r.X+r.Width
+}
@@ -0,0 +1,71 @@
+package instance
+
+import (
+	"github.com/StructsNotClasses/mim/musicarray"
+
+	"io/ioutil"
+	"strings"
+)
+
+// AddNameRule appends a rule to the naming pipeline and renames everything in the tree
+// the tengo rule runs a script file with the variables name and isDir set, and uses whatever name is set to afterwards
+func (i *Instance) AddNameRule(name string, args []string) error {
+	var rule musicarray.NameRule
+	var err error
+	if name == "tengo" && len(args) == 1 {
+		rule, err = i.tengoNameRule(args[0])
+	} else {
+		rule, err = musicarray.ParseNameRule(name, unquoteAll(args))
+	}
+	if err != nil {
+		return err
+	}
+	i.SetNaming(append(i.naming, rule))
+	return nil
+}
+
+// SetNaming replaces the naming pipeline and renames everything in the tree
+func (i *Instance) SetNaming(naming musicarray.NamePipeline) {
+	i.naming = naming
+	i.library.Rename(i.naming.Format)
+	i.refreshTree()
+}
+
+func (i *Instance) tengoNameRule(filename string) (musicarray.NameRule, error) {
+	bs, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	script := i.newScript(bs)
+	script.Add("name", "")
+	script.Add("isDir", false)
+	compiled, err := script.Compile()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(name string, isDir bool) string {
+		compiled.Set("name", name)
+		compiled.Set("isDir", isDir)
+		if err := compiled.Run(); err != nil {
+			// a broken rule shouldn't hide every name, so it just doesn't apply
+			return name
+		}
+		if result := compiled.Get("name"); result.ValueType() == "string" {
+			return result.String()
+		}
+		return name
+	}, nil
+}
+
+// unquoteAll removes the quotation marks around arguments, which allow them to contain spaces
+func unquoteAll(args []string) []string {
+	result := make([]string, len(args))
+	for n, arg := range args {
+		if len(arg) >= 2 && strings.HasPrefix(arg, "\"") && strings.HasSuffix(arg, "\"") {
+			arg = arg[1 : len(arg)-1]
+		}
+		result[n] = arg
+	}
+	return result
+}
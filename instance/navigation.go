@@ -0,0 +1,136 @@
+package instance
+
+import (
+	"github.com/StructsNotClasses/mim/instance/dirtree"
+
+	"github.com/d5/tengo/v2"
+
+	"strconv"
+)
+
+// navigation moves the selection count times, or by count pages, and returns whether it moved
+type navigation struct {
+	command string
+	tengo   string
+	move    func(t *dirtree.DirTree, count int) bool
+}
+
+// navigations are available both as commands taking an optional count, eg :page_down 2, and as Tengo functions taking one, eg pageDown(2)
+var navigations = []navigation{
+	{"select_down", "selectDown", (*dirtree.DirTree).SelectDownBy},
+	{"select_up", "selectUp", (*dirtree.DirTree).SelectUpBy},
+	{"page_down", "pageDown", (*dirtree.DirTree).PageDown},
+	{"page_up", "pageUp", (*dirtree.DirTree).PageUp},
+	{"half_page_down", "halfPageDown", (*dirtree.DirTree).HalfPageDown},
+	{"half_page_up", "halfPageUp", (*dirtree.DirTree).HalfPageUp},
+	{"select_top", "selectTop", ignoreCount((*dirtree.DirTree).SelectTop)},
+	{"select_bottom", "selectBottom", ignoreCount((*dirtree.DirTree).SelectBottom)},
+	{"next_sibling_dir", "nextSiblingDir", (*dirtree.DirTree).NextSiblingDir},
+	{"prev_sibling_dir", "prevSiblingDir", (*dirtree.DirTree).PrevSiblingDir},
+	{"first_child", "firstChild", ignoreCount((*dirtree.DirTree).FirstChild)},
+	{"last_child", "lastChild", ignoreCount((*dirtree.DirTree).LastChild)},
+}
+
+func ignoreCount(move func(t *dirtree.DirTree) bool) func(t *dirtree.DirTree, count int) bool {
+	return func(t *dirtree.DirTree, count int) bool {
+		return move(t)
+	}
+}
+
+func findNavigation(command string) (navigation, bool) {
+	for _, n := range navigations {
+		if n.command == command {
+			return n, true
+		}
+	}
+	return navigation{}, false
+}
+
+// runNavigation handles the command for a navigation, where args are the command name followed by an optional count
+func (i *Instance) runNavigation(n navigation, args []string) {
+	if !i.terminal.RequireArgCountGTE(args, 1) {
+		return
+	}
+	count := 1
+	if len(args) == 2 {
+		var err error
+		if count, err = strconv.Atoi(args[1]); err != nil || count < 1 {
+			i.terminal.ErrorPrintf("%s: the count must be a positive number but recieved '%s'.\n", n.command, args[1])
+			return
+		}
+	} else if len(args) > 2 {
+		i.terminal.ErrorPrintf("%s: expected at most 1 argument but recieved %d.\n", n.command, len(args)-1)
+		return
+	}
+	n.move(&i.tree, count)
+	i.tree.Draw()
+}
+
+// tengoNavigation creates the Tengo function for a navigation, which returns whether the selection moved
+func (i *Instance) tengoNavigation(n navigation) tengo.CallableFunc {
+	return func(args ...tengo.Object) (tengo.Object, error) {
+		if len(args) > 1 {
+			return nil, tengo.ErrWrongNumArguments
+		}
+		count := 1
+		if len(args) == 1 {
+			value, ok := args[0].(*tengo.Int)
+			if !ok {
+				return nil, tengo.ErrInvalidArgumentType{
+					Name:     "'" + n.tengo + "' argument",
+					Expected: "int",
+					Found:    args[0].TypeName(),
+				}
+			}
+			count = int(value.Value)
+		}
+		moved := n.move(&i.tree, count)
+		i.tree.Draw()
+		if moved {
+			return tengo.TrueValue, nil
+		}
+		return tengo.FalseValue, nil
+	}
+}
+
+// TengoJumpToLetter selects the next entry shown whose name starts with the character and returns whether there was one
+func (i *Instance) TengoJumpToLetter(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	var letter rune
+	switch value := args[0].(type) {
+	case *tengo.Char:
+		letter = value.Value
+	case *tengo.String:
+		runes := []rune(value.Value)
+		if len(runes) != 1 {
+			return nil, tengo.ErrInvalidArgumentType{
+				Name:     "'jumpToLetter' argument",
+				Expected: "single character string",
+				Found:    "string of length " + strconv.Itoa(len(runes)),
+			}
+		}
+		letter = runes[0]
+	default:
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "'jumpToLetter' argument",
+			Expected: "char or string",
+			Found:    args[0].TypeName(),
+		}
+	}
+	moved := i.tree.JumpToLetter(letter)
+	i.tree.Draw()
+	if moved {
+		return tengo.TrueValue, nil
+	}
+	return tengo.FalseValue, nil
+}
+
+// TengoCount returns the count typed before the key that ran the script, eg 5 for 5j, or 1 if there wasn't one
+func (i *Instance) TengoCount(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	return &tengo.Int{Value: int64(i.terminal.Count())}, nil
+}
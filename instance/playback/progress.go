@@ -0,0 +1,98 @@
+package playback
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the answers mplayer gives in slave mode to get_time_pos and get_time_length
+const (
+	positionAnswer = "ANS_TIME_POSITION="
+	lengthAnswer   = "ANS_LENGTH="
+)
+
+// Progress follows how far into the current song mplayer is
+// it is updated from mplayer's output, which is read on another goroutine
+type Progress struct {
+	lock     sync.Mutex
+	position time.Duration
+	length   time.Duration
+}
+
+// Reset starts following a new song, using length until mplayer reports its own
+func (p *Progress) Reset(length time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.position = 0
+	p.length = length
+}
+
+// Get returns the position in the song and its length, which is zero if it isn't known
+func (p *Progress) Get() (position, length time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.position, p.length
+}
+
+// Filter returns a writer for mplayer's output that records its answers about the position and length of the song and passes every other line on to out
+func (p *Progress) Filter(out io.WriteCloser) io.WriteCloser {
+	return &answerFilter{progress: p, out: out}
+}
+
+type answerFilter struct {
+	progress *Progress
+	out      io.WriteCloser
+	// partial holds the start of a line until the rest of it is written
+	partial string
+}
+
+func (f *answerFilter) Write(bs []byte) (int, error) {
+	lines := strings.Split(f.partial+string(bs), "\n")
+	f.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		if !f.progress.answer(line) {
+			if _, err := io.WriteString(f.out, line+"\n"); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(bs), nil
+}
+
+func (f *answerFilter) Close() error {
+	if f.partial != "" {
+		io.WriteString(f.out, f.partial)
+		f.partial = ""
+	}
+	return f.out.Close()
+}
+
+// answer records line if it is one of mplayer's answers
+func (p *Progress) answer(line string) bool {
+	line = strings.TrimSpace(line)
+	var target *time.Duration
+	var value string
+	switch {
+	case strings.HasPrefix(line, positionAnswer):
+		target, value = &p.position, strings.TrimPrefix(line, positionAnswer)
+	case strings.HasPrefix(line, lengthAnswer):
+		target, value = &p.length, strings.TrimPrefix(line, lengthAnswer)
+	default:
+		return false
+	}
+
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		// still an answer, just not a useful one
+		return true
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	*target = time.Duration(seconds * float64(time.Second))
+	return true
+}
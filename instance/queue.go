@@ -0,0 +1,51 @@
+package instance
+
+import (
+	"github.com/StructsNotClasses/mim/query"
+
+	"time"
+)
+
+// Enqueue adds a song to the end of the queue. Queued songs are played in order before the no-playback script is used.
+// songs are queued by path so the queue stays valid if the array changes
+func (i *Instance) Enqueue(index int) {
+	i.queue = append(i.queue, i.tree.Array()[index].Path)
+}
+
+// EnqueueQuery adds every song matching the query to the queue and returns how many were added
+func (i *Instance) EnqueueQuery(q string) (int, error) {
+	parsed, err := query.Parse(q)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, index := range parsed.Filter(i.tree.Array(), time.Now()) {
+		if !i.tree.IsDir(index) {
+			i.Enqueue(index)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (i *Instance) ClearQueue() {
+	i.queue = []string{}
+}
+
+// playNextQueued pops songs off of the queue until one is found in the tree and plays it
+// returns false if the queue ran out
+func (i *Instance) playNextQueued() bool {
+	for len(i.queue) > 0 {
+		path := i.queue[0]
+		i.queue = i.queue[1:]
+		if index, ok := i.tree.IndexOfPath(path); ok {
+			if err := i.PlayIndex(index, "queue"); err != nil {
+				i.terminal.ErrorPrintln(err)
+				continue
+			}
+			return true
+		}
+		i.terminal.InfoPrintf("queue: '%s' is no longer in the tree, skipping it.\n", path)
+	}
+	return false
+}
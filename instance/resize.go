@@ -0,0 +1,90 @@
+package instance
+
+import (
+	"github.com/StructsNotClasses/mim/instance/layout"
+
+	gnc "github.com/rthornton128/goncurses"
+
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// the go runtime installs its own SIGWINCH handler before ncurses starts, which stops ncurses from noticing resizes by itself
+// so the signal is received here and ncurses is told the new size, after which it reports KEY_RESIZE like normal
+func watchResizes() chan os.Signal {
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, syscall.SIGWINCH)
+	return resized
+}
+
+// checkResized tells ncurses the new terminal size if a resize signal was received
+func (i *Instance) checkResized() {
+	select {
+	case <-i.resized:
+		if height, width, ok := terminalSize(); ok {
+			gnc.ResizeTerm(height, width)
+		}
+	default:
+	}
+}
+
+func terminalSize() (height, width int, ok bool) {
+	var size struct {
+		rows, cols, xpixel, ypixel uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdout.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&size)))
+	if errno != 0 {
+		return 0, 0, false
+	}
+	return int(size.rows), int(size.cols), true
+}
+
+// ArrangeWindows places the panes according to the layout and the terminal size and draws everything again
+// it is used whenever the terminal is resized or the layout changes
+func (i *Instance) ArrangeWindows() {
+	height, width := i.bg.MaxYX()
+	l, err := i.layout.Compute(height, width)
+	i.panes = l
+	i.bg.Erase()
+	if err != nil {
+		// nothing useful fits, so say so until the terminal is made larger
+		// every pane is hidden meanwhile so nothing draws over the message, and they are shown again once the layout fits
+		i.mp.mpOutput.SetHidden(true)
+		i.terminal.SetHidden(true, true)
+		i.tree.SetHidden(true)
+		i.status.SetHidden(true)
+		i.bg.MovePrint(0, 0, err.Error())
+		i.bg.Refresh()
+		return
+	}
+
+	placeWindow(i.windows.Info, l.Info)
+	placeWindow(i.windows.Tree, l.Tree)
+	placeWindow(i.windows.Input, l.Input)
+	placeWindow(i.windows.Output, l.Output)
+	placeWindow(i.windows.Status, l.Status)
+	i.mp.mpOutput.SetHidden(l.Info.Hidden())
+	i.terminal.SetHidden(l.Input.Hidden(), l.Output.Hidden())
+	i.tree.SetHidden(l.Tree.Hidden())
+	i.status.SetHidden(l.Status.Hidden())
+	drawBorders(i.bg, l, i.palette)
+	i.bg.Refresh()
+
+	i.mp.mpOutput.Redraw()
+	i.terminal.Redraw()
+	i.tree.Redraw()
+	i.status.Draw()
+}
+
+// placeWindow moves and resizes a window, leaving hidden ones where they are since they aren't drawn
+// the window is shrunk first because ncurses refuses to move a window anywhere it wouldn't fit on the screen
+func placeWindow(win *gnc.Window, r layout.Rect) {
+	if r.Hidden() {
+		return
+	}
+	win.Resize(1, 1)
+	win.MoveWindow(r.Y, r.X)
+	win.Resize(r.Height, r.Width)
+}
@@ -4,26 +4,49 @@ import (
 	"github.com/StructsNotClasses/mim/script"
 
 	"github.com/d5/tengo/v2"
+	gnc "github.com/rthornton128/goncurses"
 )
 
 func (i *Instance) Run() {
+	i.syncHooks()
+	i.fireHook(hookStartup, -1, "startup", nil)
 	for shouldExit := false; !shouldExit; {
 		// check if there's a notification of playback state
 		i.mp.playbackState.Receive(i.mp.notifier)
+		if ended := i.tree.PlayingIndex(); !i.mp.playbackState.PlaybackInProgress && ended != -1 {
+			i.tree.StopPlaying()
+			i.tree.Draw()
+			i.status.SetPlaying("", false)
+			i.fireHook(hookSongEnd, ended, "finished", nil)
+		}
 
-		// if no song is playing, run the so dedicated script
-		if !i.mp.playbackState.PlaybackInProgress {
+		// if no song is playing, play the next queued song or run the so dedicated script
+		if !i.mp.playbackState.PlaybackInProgress && !i.playNextQueued() {
 			i.terminal.TryRunNoPlaybackScript()
 		}
 
+		i.updateProgress()
+
+		// ncurses reports a resize as KEY_RESIZE input once it knows about it
+		i.checkResized()
+
 		// process any new user input
-		if ch := i.GetCharNonBlocking(); ch != 0 {
+		ch := i.GetCharNonBlocking()
+		if ch == gnc.KEY_RESIZE {
+			i.ArrangeWindows()
+		} else if ch == gnc.KEY_MOUSE {
+			i.handleMouse()
+		} else if ch != 0 {
             i.terminal.InputCharacter(ch)
             if ch == '\n' {
                 shouldExit = i.HandleNewline()
             }
 		}
+
+		i.pollHooks(ch != 0 && ch != gnc.KEY_RESIZE)
+		shouldExit = shouldExit || i.exitRequested
 	}
+	i.fireHook(hookShutdown, -1, "exit", nil)
 }
 
 func (i *Instance) HandleNewline() bool {
@@ -33,8 +56,12 @@ func (i *Instance) HandleNewline() bool {
         return i.runCommand(cmd)
     } else if i.terminal.ScriptBeingWritten() {
         i.terminal.PushLineToBuffer()
+    } else if i.terminal.InRepl() {
+        line := i.terminal.CurrentLine()
+        i.terminal.ClearLine()
+        i.evalAndPrint(line)
     } else if len(i.terminal.CurrentLine()) != 1 {
-        i.terminal.InfoPrintf("Error: Non-command input '%s' entered before a begin command.\n", i.terminal.CurrentLine())
+        i.terminal.ErrorPrintf("Error: Non-command input '%s' entered before a begin command.\n", i.terminal.CurrentLine())
         i.terminal.ClearLine()
     } else {
         i.terminal.ClearLine()
@@ -43,6 +70,12 @@ func (i *Instance) HandleNewline() bool {
 }
 
 func (instance *Instance) manageScript(s script.Script) {
+	if instance.terminal.NextScriptIsHook() {
+		event, idle := instance.terminal.TakeHook()
+		instance.terminal.InfoPrintf("Attaching script: %s to the %s event\n", s.Name(), event)
+		instance.AttachHook(event, idle, s)
+		return
+	}
 	if !instance.terminal.NextScriptShouldBeBound() && !instance.terminal.NextScriptIsNoPlayback() {
 		instance.terminal.RunScript(s)
 	} else {
@@ -58,7 +91,15 @@ func (instance *Instance) manageScript(s script.Script) {
 }
 
 func (i *Instance) compileScript(bs []byte) (*tengo.Compiled, error) {
+	return i.newScript(bs).Compile()
+}
+
+// newScript creates a script with access to every function scripts can call
+func (i *Instance) newScript(bs []byte) *tengo.Script {
 	script := tengo.NewScript(bs)
+	// hookEvent holds the details of the event a hook is running for, and is undefined otherwise
+	script.Add("hookEvent", nil)
+	script.Add("store", i.tengoStore())
 	script.Add("send", i.TengoSend)
 	script.Add("selectIndex", i.TengoSelectIndex)
 	script.Add("playSelected", i.TengoPlaySelected)
@@ -68,8 +109,6 @@ func (i *Instance) compileScript(bs []byte) (*tengo.Compiled, error) {
 	script.Add("infoPrintln", i.TengoInfoPrintln)
 	script.Add("currentIndex", i.TengoCurrentIndex)
 	script.Add("randomIndex", i.TengoRandomIndex)
-	script.Add("selectUp", i.TengoSelectUp)
-	script.Add("selectDown", i.TengoSelectDown)
 	script.Add("selectEnclosing", i.TengoSelectEnclosing)
 	script.Add("toggle", i.TengoToggleDirExpansion)
 	script.Add("isDir", i.TengoIsDir)
@@ -77,11 +116,35 @@ func (i *Instance) compileScript(bs []byte) (*tengo.Compiled, error) {
 	script.Add("depth", i.TengoDepth)
 	script.Add("isExpanded", i.TengoIsExpanded)
 	script.Add("itemCount", i.TengoItemCount)
+	script.Add("entry", i.TengoEntry)
+	script.Add("nowPlaying", i.TengoNowPlaying)
 	script.Add("setSearch", i.TengoSetSearch)
 	script.Add("nextMatch", i.TengoNextMatch)
 	script.Add("prevMatch", i.TengoPrevMatch)
 	script.Add("getLine", i.TengoGetLine)
 	script.Add("getChar", i.TengoGetChar)
+	script.Add("query", i.TengoQuery)
+	script.Add("enqueue", i.TengoEnqueue)
+	script.Add("jumpToPlaying", i.TengoJumpToPlaying)
+	script.Add("marked", i.TengoMarked)
+	script.Add("toggleMark", i.TengoToggleMark)
+	script.Add("setMark", i.TengoSetMark)
+	script.Add("gotoMark", i.TengoGotoMark)
+	for _, n := range navigations {
+		script.Add(n.tengo, i.tengoNavigation(n))
+	}
+	script.Add("jumpToLetter", i.TengoJumpToLetter)
+	script.Add("expandAll", i.TengoExpandAll)
+	script.Add("collapseAll", i.TengoCollapseAll)
+	script.Add("collapseOthers", i.TengoCollapseOthers)
+	script.Add("expandToDepth", i.TengoExpandToDepth)
+	script.Add("expandRecursive", i.TengoExpandRecursive)
+	script.Add("count", i.TengoCount)
+	script.Add("command", i.TengoCommand)
+	script.Add("followPlayback", i.TengoFollowPlayback)
+	script.Add("playingIndex", i.TengoPlayingIndex)
+	script.Add("nextSong", i.TengoNextSong)
+	script.Add("prevSong", i.TengoPrevSong)
 
-	return script.Compile()
+	return script
 }
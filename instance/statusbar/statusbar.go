@@ -0,0 +1,134 @@
+package statusbar
+
+import (
+	"github.com/StructsNotClasses/mim/instance/theme"
+	"github.com/StructsNotClasses/mim/textwidth"
+	"github.com/StructsNotClasses/mim/titleformat"
+
+	gnc "github.com/rthornton128/goncurses"
+
+	"strings"
+	"time"
+)
+
+// the progress bar takes a quarter of the status bar, within these limits, and is left out on narrow terminals
+const (
+	minBarWidth = 10
+	maxBarWidth = 40
+)
+
+// StatusBar is a single row showing what is playing and how far into it playback is
+type StatusBar struct {
+	win      *gnc.Window
+	palette  *theme.Palette
+	text     string
+	playing  bool
+	hidden   bool
+	position time.Duration
+	length   time.Duration
+
+	// the columns the bar itself was drawn in, so clicks on it can be turned into a position in the song
+	barX     int
+	barWidth int
+}
+
+func New(win *gnc.Window, palette *theme.Palette) StatusBar {
+	return StatusBar{
+		win:     win,
+		palette: palette,
+	}
+}
+
+// SetPlaying shows text as the song being played, or that nothing is playing if playing is false
+func (s *StatusBar) SetPlaying(text string, playing bool) {
+	s.text = text
+	s.playing = playing
+	s.position = 0
+	s.length = 0
+	s.Draw()
+}
+
+// SetText replaces the text shown for the song being played, keeping the progress
+func (s *StatusBar) SetText(text string) {
+	s.text = text
+	s.Draw()
+}
+
+// SetProgress shows how far into the song playback is, drawing again only if a different second would be shown
+// a zero length means the length isn't known, in which case no bar is shown
+func (s *StatusBar) SetProgress(position, length time.Duration) {
+	if position/time.Second == s.position/time.Second && length/time.Second == s.length/time.Second {
+		return
+	}
+	s.position = position
+	s.length = length
+	s.Draw()
+}
+
+func (s *StatusBar) Draw() {
+	if s.hidden {
+		return
+	}
+	_, width := s.win.MaxYX()
+	line := "Stopped"
+	attributes := s.palette.Attributes(theme.Song)
+	if s.playing {
+		line = "Playing: " + s.text
+		attributes = s.palette.Attributes(theme.Playing)
+	}
+
+	// printing in the last column of the last row would scroll the window, so leave it empty
+	bar := s.progressBar(width - 1)
+	s.win.Erase()
+	s.win.AttrOn(attributes)
+	s.win.MovePrint(0, 0, textwidth.Pad(line, width-1-textwidth.String(bar)))
+	s.win.AttrOff(attributes)
+	s.win.Print(bar)
+	s.win.Refresh()
+}
+
+// progressBar returns the position, bar and length to show at the end of a status bar with the given width, eg " 1:02 [====------] 3:45"
+func (s *StatusBar) progressBar(width int) string {
+	s.barWidth = 0
+	if !s.playing || s.length <= 0 || width/4 < minBarWidth {
+		return ""
+	}
+	position := s.position
+	if position > s.length {
+		position = s.length
+	}
+
+	barWidth := width / 4
+	if barWidth > maxBarWidth {
+		barWidth = maxBarWidth
+	}
+	inner := barWidth - 2
+	filled := int(float64(inner) * float64(position) / float64(s.length))
+	suffix := " " + titleformat.FormatDuration(s.length)
+
+	// the bar is drawn right aligned
+	s.barX = width - len(suffix) - barWidth
+	s.barWidth = barWidth
+	return " " + titleformat.FormatDuration(position) + " [" + strings.Repeat("=", filled) + strings.Repeat("-", inner-filled) + "]" + suffix
+}
+
+// SeekFraction returns how far into the song a click at column x of the status bar points, if the bar was drawn there
+func (s StatusBar) SeekFraction(x int) (float64, bool) {
+	if s.barWidth == 0 || x < s.barX || x >= s.barX+s.barWidth {
+		return 0, false
+	}
+	// the brackets count as the very start and end of the song
+	inner := s.barWidth - 2
+	fraction := float64(x-s.barX-1) / float64(inner-1)
+	if fraction < 0 {
+		fraction = 0
+	} else if fraction > 1 {
+		fraction = 1
+	}
+	return fraction, true
+}
+
+// SetHidden stops the status bar from drawing while its pane isn't shown
+func (s *StatusBar) SetHidden(hidden bool) {
+	s.hidden = hidden
+}
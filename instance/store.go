@@ -0,0 +1,252 @@
+package instance
+
+import (
+	"github.com/d5/tengo/v2"
+
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// the store is a set of values shared by every script, which are kept as plain go values so they can't be changed through a value a script got from it
+// they are limited to what can be saved as json: ints, floats, strings, bools and arrays and maps of them
+
+// tengoStore returns the 'store' map scripts use to reach the store
+// store.get(key, default?) returns the value or the default, which is undefined if it isn't given
+// store.set(key, value) sets the value, store.delete(key) removes it and returns whether it was there and store.keys() returns the keys in order
+func (i *Instance) tengoStore() *tengo.ImmutableMap {
+	return &tengo.ImmutableMap{Value: map[string]tengo.Object{
+		"get":    &tengo.UserFunction{Name: "get", Value: i.TengoStoreGet},
+		"set":    &tengo.UserFunction{Name: "set", Value: i.TengoStoreSet},
+		"delete": &tengo.UserFunction{Name: "delete", Value: i.TengoStoreDelete},
+		"keys":   &tengo.UserFunction{Name: "keys", Value: i.TengoStoreKeys},
+	}}
+}
+
+func (i *Instance) TengoStoreGet(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	key, err := storeKey("store.get", args[0])
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := i.store[key]; ok {
+		return fromStored(value), nil
+	} else if len(args) == 2 {
+		return args[1], nil
+	}
+	return tengo.UndefinedValue, nil
+}
+
+func (i *Instance) TengoStoreSet(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 2 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	key, err := storeKey("store.set", args[0])
+	if err != nil {
+		return nil, err
+	}
+	value, err := toStored(args[1])
+	if err != nil {
+		return nil, err
+	}
+	// floats such as NaN and infinity can't be saved as json
+	if _, err := json.Marshal(value); err != nil {
+		return nil, errors.New(fmt.Sprintf("store.set: the value for '%s' can't be saved: %v.", key, err))
+	}
+	old, existed := i.store[key]
+	i.store[key] = value
+	if err := i.saveStore(); err != nil {
+		i.restoreStoreKey(key, old, existed)
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *Instance) TengoStoreDelete(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	key, err := storeKey("store.delete", args[0])
+	if err != nil {
+		return nil, err
+	}
+	old, ok := i.store[key]
+	if !ok {
+		return tengo.FalseValue, nil
+	}
+	delete(i.store, key)
+	if err := i.saveStore(); err != nil {
+		i.restoreStoreKey(key, old, true)
+		return nil, err
+	}
+	return tengo.TrueValue, nil
+}
+
+// restoreStoreKey puts back what a key held before a change that couldn't be saved, so the store keeps matching its file
+func (i *Instance) restoreStoreKey(key string, old interface{}, existed bool) {
+	if existed {
+		i.store[key] = old
+	} else {
+		delete(i.store, key)
+	}
+}
+
+func (i *Instance) TengoStoreKeys(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	keys := &tengo.Array{}
+	for _, key := range i.storeKeys() {
+		keys.Value = append(keys.Value, &tengo.String{Value: key})
+	}
+	return keys, nil
+}
+
+func storeKey(function string, key tengo.Object) (string, error) {
+	if s, ok := key.(*tengo.String); ok {
+		return s.Value, nil
+	}
+	return "", tengo.ErrInvalidArgumentType{
+		Name:     fmt.Sprintf("'%s' key", function),
+		Expected: "string",
+		Found:    key.TypeName(),
+	}
+}
+
+// toStored copies a tengo value into the plain go values the store holds
+func toStored(o tengo.Object) (interface{}, error) {
+	switch o := o.(type) {
+	case *tengo.Int:
+		return o.Value, nil
+	case *tengo.Float:
+		return o.Value, nil
+	case *tengo.String:
+		return o.Value, nil
+	case *tengo.Bool:
+		return !o.IsFalsy(), nil
+	case *tengo.Array:
+		return toStoredArray(o.Value)
+	case *tengo.ImmutableArray:
+		return toStoredArray(o.Value)
+	case *tengo.Map:
+		return toStoredMap(o.Value)
+	case *tengo.ImmutableMap:
+		return toStoredMap(o.Value)
+	}
+	return nil, tengo.ErrInvalidArgumentType{
+		Name:     "'store.set' value",
+		Expected: "int, float, string, bool, array or map",
+		Found:    o.TypeName(),
+	}
+}
+
+func toStoredArray(elements []tengo.Object) (interface{}, error) {
+	arr := make([]interface{}, len(elements))
+	for n, e := range elements {
+		v, err := toStored(e)
+		if err != nil {
+			return nil, err
+		}
+		arr[n] = v
+	}
+	return arr, nil
+}
+
+func toStoredMap(elements map[string]tengo.Object) (interface{}, error) {
+	m := make(map[string]interface{}, len(elements))
+	for k, e := range elements {
+		v, err := toStored(e)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+// fromStored makes a new tengo value from a stored one, which is also how values read from the store file are converted
+// json numbers without a fraction become ints so indices survive being saved
+func fromStored(v interface{}) tengo.Object {
+	switch v := v.(type) {
+	case int64:
+		return &tengo.Int{Value: v}
+	case float64:
+		return &tengo.Float{Value: v}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return &tengo.Int{Value: n}
+		}
+		f, _ := v.Float64()
+		return &tengo.Float{Value: f}
+	case string:
+		return &tengo.String{Value: v}
+	case bool:
+		if v {
+			return tengo.TrueValue
+		}
+		return tengo.FalseValue
+	case []interface{}:
+		arr := &tengo.Array{Value: make([]tengo.Object, len(v))}
+		for n, e := range v {
+			arr.Value[n] = fromStored(e)
+		}
+		return arr
+	case map[string]interface{}:
+		m := &tengo.Map{Value: make(map[string]tengo.Object, len(v))}
+		for k, e := range v {
+			m.Value[k] = fromStored(e)
+		}
+		return m
+	}
+	return tengo.UndefinedValue
+}
+
+func (i *Instance) storeKeys() []string {
+	keys := []string{}
+	for key := range i.store {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// LoadStore reads the store from a json file and remembers it so the store is saved back to it whenever it changes
+// values already in the store are kept unless the file has the same key
+func (i *Instance) LoadStore(filename string) error {
+	i.storeFile = filename
+	contents, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		// the file will be created once something is stored
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.UseNumber()
+	loaded := make(map[string]interface{})
+	if err := decoder.Decode(&loaded); err != nil {
+		return errors.New(fmt.Sprintf("store: '%s' is not a json object: %v.", filename, err))
+	}
+	for key, value := range loaded {
+		i.store[key] = value
+	}
+	return nil
+}
+
+func (i *Instance) saveStore() error {
+	if i.storeFile == "" {
+		return nil
+	}
+	contents, err := json.MarshalIndent(i.store, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(i.storeFile, append(contents, '\n'), 0644)
+}
@@ -0,0 +1,145 @@
+package instance
+
+import (
+	"github.com/d5/tengo/v2"
+
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		value tengo.Object
+		// what store.get returns in the same session, and after the store is saved and loaded again
+		got, loaded tengo.Object
+	}{
+		{"int", &tengo.Int{Value: 3}, nil, nil},
+		{"large int", &tengo.Int{Value: -1 << 62}, nil, nil},
+		{"float", &tengo.Float{Value: 1.5}, nil, nil},
+		// json doesn't tell a whole float from an int
+		{"whole float", &tengo.Float{Value: 2}, nil, &tengo.Int{Value: 2}},
+		{"string", &tengo.String{Value: "héllo \"world\""}, nil, nil},
+		{"true", tengo.TrueValue, nil, nil},
+		{"false", tengo.FalseValue, nil, nil},
+		{"empty array", &tengo.Array{}, nil, nil},
+		{"empty map", &tengo.Map{Value: map[string]tengo.Object{}}, nil, nil},
+		{
+			"nested",
+			&tengo.Array{Value: []tengo.Object{
+				&tengo.Int{Value: 1},
+				&tengo.String{Value: "a"},
+				&tengo.Map{Value: map[string]tengo.Object{
+					"b": &tengo.Array{Value: []tengo.Object{tengo.TrueValue, &tengo.Float{Value: 0.25}}},
+				}},
+			}},
+			nil, nil,
+		},
+		// immutable values come back as ordinary ones that can be changed
+		{
+			"immutable array",
+			&tengo.ImmutableArray{Value: []tengo.Object{&tengo.Int{Value: 1}}},
+			&tengo.Array{Value: []tengo.Object{&tengo.Int{Value: 1}}},
+			&tengo.Array{Value: []tengo.Object{&tengo.Int{Value: 1}}},
+		},
+		{
+			"immutable map",
+			&tengo.ImmutableMap{Value: map[string]tengo.Object{"a": &tengo.String{Value: "b"}}},
+			&tengo.Map{Value: map[string]tengo.Object{"a": &tengo.String{Value: "b"}}},
+			&tengo.Map{Value: map[string]tengo.Object{"a": &tengo.String{Value: "b"}}},
+		},
+	}
+
+	filename := filepath.Join(t.TempDir(), "store.json")
+	i := &Instance{store: make(map[string]interface{}), storeFile: filename}
+	for _, test := range tests {
+		if _, err := i.TengoStoreSet(&tengo.String{Value: test.name}, test.value); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+	}
+
+	loaded := &Instance{store: make(map[string]interface{})}
+	if err := loaded.LoadStore(filename); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		want := test.got
+		if want == nil {
+			want = test.value
+		}
+		if got, _ := i.TengoStoreGet(&tengo.String{Value: test.name}); got.TypeName() != want.TypeName() || !got.Equals(want) {
+			t.Errorf("%s: got %s %v, want %s %v", test.name, got.TypeName(), got, want.TypeName(), want)
+		}
+		if test.loaded != nil {
+			want = test.loaded
+		}
+		if got, _ := loaded.TengoStoreGet(&tengo.String{Value: test.name}); got.TypeName() != want.TypeName() || !got.Equals(want) {
+			t.Errorf("%s after loading: got %s %v, want %s %v", test.name, got.TypeName(), got, want.TypeName(), want)
+		}
+	}
+}
+
+func TestStoreSetErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []tengo.Object
+	}{
+		{"undefined", []tengo.Object{&tengo.String{Value: "k"}, tengo.UndefinedValue}},
+		{"function", []tengo.Object{&tengo.String{Value: "k"}, &tengo.UserFunction{Name: "f"}}},
+		{"char", []tengo.Object{&tengo.String{Value: "k"}, &tengo.Char{Value: 'a'}}},
+		{"undefined in an array", []tengo.Object{&tengo.String{Value: "k"}, &tengo.Array{Value: []tengo.Object{&tengo.Int{}, tengo.UndefinedValue}}}},
+		{"function in a map", []tengo.Object{&tengo.String{Value: "k"}, &tengo.Map{Value: map[string]tengo.Object{"f": &tengo.UserFunction{Name: "f"}}}}},
+		// floats json can't hold
+		{"NaN", []tengo.Object{&tengo.String{Value: "k"}, &tengo.Float{Value: math.NaN()}}},
+		{"infinity", []tengo.Object{&tengo.String{Value: "k"}, &tengo.Float{Value: math.Inf(-1)}}},
+		{"NaN in a map", []tengo.Object{&tengo.String{Value: "k"}, &tengo.Map{Value: map[string]tengo.Object{"a": &tengo.Float{Value: math.NaN()}}}}},
+		{"key that isn't a string", []tengo.Object{&tengo.Int{Value: 1}, &tengo.Int{Value: 1}}},
+		{"no value", []tengo.Object{&tengo.String{Value: "k"}}},
+	}
+
+	for _, test := range tests {
+		i := &Instance{store: make(map[string]interface{})}
+		if _, err := i.TengoStoreSet(test.args...); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+		if len(i.store) != 0 {
+			t.Errorf("%s: got %v in the store after an error", test.name, i.store)
+		}
+	}
+}
+
+func TestFromStoredNumbers(t *testing.T) {
+	tests := []struct {
+		number string
+		want   tengo.Object
+	}{
+		{"12", &tengo.Int{Value: 12}},
+		{"-7", &tengo.Int{Value: -7}},
+		{"1.5", &tengo.Float{Value: 1.5}},
+		{"1e3", &tengo.Float{Value: 1000}},
+		// too large for an int
+		{"18446744073709551616", &tengo.Float{Value: 18446744073709551616}},
+	}
+
+	for _, test := range tests {
+		got := fromStored(json.Number(test.number))
+		if got.TypeName() != test.want.TypeName() || !got.Equals(test.want) {
+			t.Errorf("fromStored(%s) = %s %v, want %s %v", test.number, got.TypeName(), got, test.want.TypeName(), test.want)
+		}
+	}
+}
+
+func TestStoreGetCopies(t *testing.T) {
+	i := &Instance{store: make(map[string]interface{})}
+	key := &tengo.String{Value: "list"}
+	if _, err := i.TengoStoreSet(key, &tengo.Array{Value: []tengo.Object{&tengo.Int{Value: 1}}}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := i.TengoStoreGet(key)
+	got.(*tengo.Array).Value[0] = &tengo.Int{Value: 2}
+	if again, _ := i.TengoStoreGet(key); !again.Equals(&tengo.Array{Value: []tengo.Object{&tengo.Int{Value: 1}}}) {
+		t.Errorf("changing a value from store.get changed the store to %v", again)
+	}
+}
@@ -0,0 +1,103 @@
+package instance
+
+import (
+	"github.com/StructsNotClasses/mim/musicarray"
+	"github.com/StructsNotClasses/mim/titleformat"
+
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const defaultStatusTemplate = "[%artist% - ]%title%[ (%duration%)]"
+const defaultPlaylistTemplate = "[%artist% - ]%title%"
+
+// SetTemplate changes the template used for one of song lines, directory lines, the status bar or exported playlists
+// an empty template shows entries by name
+func (i *Instance) SetTemplate(target, template string) error {
+	t, err := titleformat.Parse(template)
+	if err != nil {
+		return err
+	}
+	switch target {
+	case "song":
+		i.tree.SetSongTemplate(t)
+		i.tree.Redraw()
+	case "directory":
+		i.tree.SetDirectoryTemplate(t)
+		i.tree.Redraw()
+	case "status":
+		i.statusTemplate = t
+		if i.mp.playbackState.PlaybackInProgress {
+			i.status.SetText(i.formatEntry(t, i.tree.PlayingIndex()))
+		}
+	case "playlist":
+		i.playlistTemplate = t
+	default:
+		return errors.New(fmt.Sprintf("template: '%s' is not a template; the templates are song, directory, status and playlist.", target))
+	}
+	return nil
+}
+
+// formatEntry fills in the template for the entry of the tree at index
+func (i *Instance) formatEntry(t titleformat.Template, index int) string {
+	return titleformat.FormatEntry(t, i.tree.Array(), index)
+}
+
+// ExportPlaylist writes the songs in the selected directory, or the selected song, to an extended m3u playlist
+func (i *Instance) ExportPlaylist(filename string) (int, error) {
+	index := i.tree.CurrentIndex()
+	if !i.tree.IsInRange(index) {
+		return 0, errors.New("export_playlist: nothing is selected.")
+	}
+	indices := []int{index}
+	if i.tree.IsDir(index) {
+		indices = []int{}
+		arr := i.tree.Array()
+		for n := index + 1; n < arr[index].Dir.EndDirectoryIndex; n++ {
+			if arr[n].Type == musicarray.SongEntry {
+				indices = append(indices, n)
+			}
+		}
+	}
+	return len(indices), i.writePlaylist(filename, indices)
+}
+
+// ExportQueue writes the queued songs to an extended m3u playlist
+func (i *Instance) ExportQueue(filename string) (int, error) {
+	indices := []int{}
+	for _, path := range i.queue {
+		if index, ok := i.tree.IndexOfPath(path); ok {
+			indices = append(indices, index)
+		}
+	}
+	return len(indices), i.writePlaylist(filename, indices)
+}
+
+// writePlaylist returns the first error from writing or closing the file, so a playlist cut short by a full disk isn't reported as written
+func (i *Instance) writePlaylist(filename string, indices []int) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	arr := i.tree.Array()
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "#EXTM3U")
+	for _, index := range indices {
+		seconds := -1
+		if arr[index].Song.Duration > 0 {
+			seconds = int(arr[index].Song.Duration / time.Second)
+		}
+		fmt.Fprintf(w, "#EXTINF:%d,%s\n", seconds, i.formatEntry(i.playlistTemplate, index))
+		fmt.Fprintln(w, arr[index].Path)
+	}
+	// the writer keeps the first error, so flushing reports any write that failed
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -1,10 +1,16 @@
 package instance
 
 import (
+	"github.com/StructsNotClasses/mim/musicarray"
+	"github.com/StructsNotClasses/mim/query"
+
 	"github.com/d5/tengo/v2"
 
+	"errors"
+	"fmt"
 	"math/rand"
 	"strings"
+	"time"
 )
 
 func (i *Instance) TengoSend(args ...tengo.Object) (tengo.Object, error) {
@@ -47,7 +53,7 @@ func (i *Instance) TengoPlaySelected(args ...tengo.Object) (tengo.Object, error)
 	if len(args) != 0 {
 		return nil, tengo.ErrWrongNumArguments
 	}
-	err := i.PlayIndex(int(i.tree.CurrentIndex()))
+	err := i.PlayIndex(int(i.tree.CurrentIndex()), "script")
 	return nil, err
 }
 
@@ -56,7 +62,7 @@ func (i *Instance) TengoPlayIndex(args ...tengo.Object) (tengo.Object, error) {
 		return nil, tengo.ErrWrongNumArguments
 	}
 	if value, ok := args[0].(*tengo.Int); ok {
-		err := i.PlayIndex(int(value.Value))
+		err := i.PlayIndex(int(value.Value), "script")
 		return nil, err
 	} else {
 		return nil, tengo.ErrInvalidArgumentType{
@@ -106,26 +112,6 @@ func (i *Instance) TengoRandomIndex(args ...tengo.Object) (tengo.Object, error)
 	return &tengo.Int{Value: int64(rnum)}, nil
 }
 
-func (i *Instance) TengoSelectUp(args ...tengo.Object) (tengo.Object, error) {
-	if len(args) != 0 {
-		return nil, tengo.ErrWrongNumArguments
-	}
-
-	i.tree.SelectUp()
-	i.tree.Draw()
-	return nil, nil
-}
-
-func (i *Instance) TengoSelectDown(args ...tengo.Object) (tengo.Object, error) {
-	if len(args) != 0 {
-		return nil, tengo.ErrWrongNumArguments
-	}
-
-	i.tree.SelectDown()
-	i.tree.Draw()
-	return nil, nil
-}
-
 func (i *Instance) TengoSelectEnclosing(args ...tengo.Object) (tengo.Object, error) {
 	if len(args) != 0 {
 		return nil, tengo.ErrWrongNumArguments
@@ -245,6 +231,7 @@ func (i *Instance) TengoSetSearch(args ...tengo.Object) (tengo.Object, error) {
 	if ts, ok := args[0].(*tengo.String); ok {
 		ss := strings.TrimSuffix(strings.TrimPrefix(ts.String(), "\""), "\"")
 		i.tree.SetSearch(ss)
+		i.tree.Draw()
 		return nil, nil
 	} else {
 		return nil, tengo.ErrInvalidArgumentType{
@@ -320,3 +307,374 @@ func (i *Instance) TengoGetChar(args ...tengo.Object) (tengo.Object, error) {
 	char := i.GetCharBlocking()
 	return &tengo.Char{Value: char}, nil
 }
+
+// TengoQuery returns an array of the indices of all entries matching the query string provided
+func (i *Instance) TengoQuery(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+
+	if ts, ok := args[0].(*tengo.String); ok {
+		q, err := query.Parse(ts.Value)
+		if err != nil {
+			return nil, err
+		}
+		result := &tengo.Array{}
+		for _, index := range q.Filter(i.tree.Array(), time.Now()) {
+			result.Value = append(result.Value, &tengo.Int{Value: int64(index)})
+		}
+		return result, nil
+	} else {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "'query' argument",
+			Expected: "string",
+			Found:    args[0].TypeName(),
+		}
+	}
+}
+
+func (i *Instance) TengoEnqueue(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+
+	if value, ok := args[0].(*tengo.Int); ok {
+		index := int(value.Value)
+		if !i.tree.IsInRange(index) || i.tree.IsDir(index) {
+			return nil, errors.New(fmt.Sprintf("enqueue: %d is not the index of a song.", index))
+		}
+		i.Enqueue(index)
+		return nil, nil
+	} else {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "'enqueue' argument",
+			Expected: "int",
+			Found:    args[0].TypeName(),
+		}
+	}
+}
+
+// TengoMarked returns an array of the indices of the marked entries in the order they appear in the tree
+func (i *Instance) TengoMarked(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	result := &tengo.Array{}
+	for _, index := range i.tree.Marked() {
+		result.Value = append(result.Value, &tengo.Int{Value: int64(index)})
+	}
+	return result, nil
+}
+
+// TengoToggleMark marks the entry at the index, or unmarks it if it is already marked
+func (i *Instance) TengoToggleMark(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	value, ok := args[0].(*tengo.Int)
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "'toggleMark' argument",
+			Expected: "int",
+			Found:    args[0].TypeName(),
+		}
+	}
+	i.tree.ToggleMark(int(value.Value))
+	i.tree.Draw()
+	return nil, nil
+}
+
+// TengoSetMark records the selected entry under a name, like :mark <name>
+func (i *Instance) TengoSetMark(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	name, ok := tengo.ToString(args[0])
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "'setMark' argument",
+			Expected: "string",
+			Found:    args[0].TypeName(),
+		}
+	}
+	return nil, i.SetNamedMark(name)
+}
+
+// TengoGotoMark selects the entry recorded under a name and returns whether it could be found
+func (i *Instance) TengoGotoMark(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	name, ok := tengo.ToString(args[0])
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "'gotoMark' argument",
+			Expected: "string",
+			Found:    args[0].TypeName(),
+		}
+	}
+	if err := i.GotoNamedMark(name); err != nil {
+		return tengo.FalseValue, nil
+	}
+	return tengo.TrueValue, nil
+}
+
+// TengoJumpToPlaying selects the song being played and returns whether anything is playing
+func (i *Instance) TengoJumpToPlaying(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	if i.JumpToPlaying() {
+		return tengo.TrueValue, nil
+	}
+	return tengo.FalseValue, nil
+}
+
+// TengoFollowPlayback returns whether the selection follows playback, first changing it if a bool is given
+func (i *Instance) TengoFollowPlayback(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) > 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	if len(args) == 1 {
+		if v, ok := args[0].(*tengo.Bool); ok {
+			i.followPlayback = !v.IsFalsy()
+		} else {
+			return nil, tengo.ErrInvalidArgumentType{
+				Name:     "'followPlayback' argument",
+				Expected: "bool",
+				Found:    args[0].TypeName(),
+			}
+		}
+	}
+	if i.followPlayback {
+		return tengo.TrueValue, nil
+	}
+	return tengo.FalseValue, nil
+}
+
+// TengoPlayingIndex returns the index of the song being played, or the last one played once it has finished, or -1 if nothing has played
+// unlike currentIndex it isn't changed by moving the selection, so scripts can continue playback from it
+func (i *Instance) TengoPlayingIndex(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	return &tengo.Int{Value: int64(i.tree.LastPlayedIndex())}, nil
+}
+
+// TengoNextSong returns the index of the first song after the given index, or -1 if there isn't one
+func (i *Instance) TengoNextSong(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	if value, ok := args[0].(*tengo.Int); ok {
+		return &tengo.Int{Value: int64(i.tree.NextSong(int(value.Value)))}, nil
+	} else {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "'nextSong' argument",
+			Expected: "int",
+			Found:    args[0].TypeName(),
+		}
+	}
+}
+
+// TengoPrevSong returns the index of the last song before the given index, or -1 if there isn't one
+func (i *Instance) TengoPrevSong(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	if value, ok := args[0].(*tengo.Int); ok {
+		return &tengo.Int{Value: int64(i.tree.PrevSong(int(value.Value)))}, nil
+	} else {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "'prevSong' argument",
+			Expected: "int",
+			Found:    args[0].TypeName(),
+		}
+	}
+}
+
+// TengoExpandAll opens every directory, drawing the tree once afterwards
+func (i *Instance) TengoExpandAll(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	i.tree.ExpandAll()
+	i.tree.Draw()
+	return nil, nil
+}
+
+// TengoCollapseAll closes every directory, drawing the tree once afterwards
+func (i *Instance) TengoCollapseAll(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	i.tree.CollapseAll()
+	i.tree.Draw()
+	return nil, nil
+}
+
+// TengoCollapseOthers closes every directory except those containing the selection
+func (i *Instance) TengoCollapseOthers(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	i.tree.CollapseOthers()
+	i.tree.Draw()
+	return nil, nil
+}
+
+// TengoExpandToDepth opens every directory shallower than the depth given and closes the rest
+func (i *Instance) TengoExpandToDepth(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	depth, ok := args[0].(*tengo.Int)
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "'expandToDepth' argument",
+			Expected: "int",
+			Found:    args[0].TypeName(),
+		}
+	}
+	i.tree.ExpandToDepth(int(depth.Value))
+	i.tree.Draw()
+	return nil, nil
+}
+
+// TengoExpandRecursive opens a directory and every directory inside it, using the selection if no index is given
+// it returns false if the entry isn't a directory
+func (i *Instance) TengoExpandRecursive(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) > 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	index := i.tree.CurrentIndex()
+	if len(args) == 1 {
+		value, ok := args[0].(*tengo.Int)
+		if !ok {
+			return nil, tengo.ErrInvalidArgumentType{
+				Name:     "'expandRecursive' argument",
+				Expected: "int",
+				Found:    args[0].TypeName(),
+			}
+		}
+		index = int(value.Value)
+	}
+	expanded := i.tree.ExpandRecursive(index)
+	i.tree.Draw()
+	if expanded {
+		return tengo.TrueValue, nil
+	}
+	return tengo.FalseValue, nil
+}
+
+// TengoEntry returns an immutable map describing the entry at the index, or undefined if the index is out of range
+// the map has the entry's index, name, path, depth and type, which is "directory" or "song", and the indices of its parent (-1 for the root) and of the entry after it and everything inside it
+// directories also have their item count and whether they are virtual, and songs their tags, duration in seconds and play count
+func (i *Instance) TengoEntry(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	value, ok := args[0].(*tengo.Int)
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "'entry' argument",
+			Expected: "int",
+			Found:    args[0].TypeName(),
+		}
+	}
+	return i.entryObject(int(value.Value)), nil
+}
+
+// TengoNowPlaying returns the entry being played like entry does, or undefined if nothing is playing
+func (i *Instance) TengoNowPlaying(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	return i.entryObject(i.tree.PlayingIndex()), nil
+}
+
+func (i *Instance) entryObject(index int) tengo.Object {
+	if !i.tree.IsInRange(index) {
+		return tengo.UndefinedValue
+	}
+	e := i.tree.Array()[index]
+	m := map[string]tengo.Object{
+		"index":       &tengo.Int{Value: int64(index)},
+		"name":        &tengo.String{Value: e.Name},
+		"path":        &tengo.String{Value: e.Path},
+		"depth":       &tengo.Int{Value: int64(e.Depth)},
+		"parentIndex": &tengo.Int{Value: int64(e.ParentIndex)},
+	}
+	if e.Type == musicarray.DirectoryEntry {
+		m["type"] = &tengo.String{Value: "directory"}
+		m["itemCount"] = &tengo.Int{Value: int64(e.Dir.ItemCount)}
+		m["endIndex"] = &tengo.Int{Value: int64(e.Dir.EndDirectoryIndex)}
+		m["virtual"] = tengo.FalseValue
+		if e.Dir.Virtual {
+			m["virtual"] = tengo.TrueValue
+		}
+		m["tags"] = &tengo.ImmutableMap{Value: map[string]tengo.Object{}}
+		return &tengo.ImmutableMap{Value: m}
+	}
+
+	tags := make(map[string]tengo.Object, len(e.Song.Tags))
+	for k, v := range e.Song.Tags {
+		tags[k] = &tengo.String{Value: v}
+	}
+	m["type"] = &tengo.String{Value: "song"}
+	m["itemCount"] = &tengo.Int{Value: 0}
+	m["endIndex"] = &tengo.Int{Value: int64(index + 1)}
+	m["tags"] = &tengo.ImmutableMap{Value: tags}
+	m["duration"] = &tengo.Float{Value: e.Song.Duration.Seconds()}
+	playCount := 0
+	if e.Song.Stats != nil {
+		playCount = e.Song.Stats.PlayCount
+	}
+	m["playCount"] = &tengo.Int{Value: int64(playCount)}
+	return &tengo.ImmutableMap{Value: m}
+}
+
+// maxCommandDepth is how deeply scripts running commands that run scripts can nest before command gives up, so a script that loads itself can't recurse forever
+const maxCommandDepth = 16
+
+// TengoCommand runs a command as if it had been typed, eg command(":set_search boc"), returning what it printed without the last newline
+// if the command printed any errors they are returned as an error instead, which is_error can check for
+// :exit doesn't stop the script but mim exits once the script finishes
+// commands that wait on what is typed next, such as :begin, :bind, :on and :repl, are undone and return an error since a script can't type it
+// :load_config still works as long as the file finishes every script it begins
+func (i *Instance) TengoCommand(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	cmd, ok := args[0].(*tengo.String)
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "'command' argument",
+			Expected: "string",
+			Found:    args[0].TypeName(),
+		}
+	}
+	if !strings.HasPrefix(cmd.Value, ":") {
+		return &tengo.Error{Value: &tengo.String{Value: fmt.Sprintf("command: '%s' is not a command; commands start with ':'.", cmd.Value)}}, nil
+	}
+	if i.commandDepth >= maxCommandDepth {
+		return &tengo.Error{Value: &tengo.String{Value: fmt.Sprintf("command: scripts and commands are nested more than %d deep, which is probably a script running itself.", maxCommandDepth)}}, nil
+	}
+
+	i.commandDepth++
+	defer func() { i.commandDepth-- }()
+	pending := i.terminal.Pending()
+	output, errs := i.terminal.Capture(func() {
+		if i.runCommand(cmd.Value) {
+			i.exitRequested = true
+		}
+	})
+	if i.terminal.Pending() != pending {
+		i.terminal.RestorePending(pending)
+		return &tengo.Error{Value: &tengo.String{Value: fmt.Sprintf("command: '%s' waits on what is typed next, which a script can't provide.", cmd.Value)}}, nil
+	}
+	if errs != "" {
+		return &tengo.Error{Value: &tengo.String{Value: strings.TrimSuffix(errs, "\n")}}, nil
+	}
+	return &tengo.String{Value: strings.TrimSuffix(output, "\n")}, nil
+}
@@ -1,11 +1,16 @@
 package terminal
 
 import (
+	"github.com/StructsNotClasses/mim/instance/theme"
 	"github.com/StructsNotClasses/mim/script"
+	"github.com/StructsNotClasses/mim/windowwriter"
 
 	gnc "github.com/rthornton128/goncurses"
 
     "fmt"
+    "strings"
+    "time"
+    "unicode/utf8"
 )
 
 type InputMode int
@@ -20,6 +25,12 @@ type OptionalScript struct {
     s script.Script
 }
 
+// capture collects what would be printed, eg while a script runs a command and wants its output
+type capture struct {
+    output strings.Builder
+    errors strings.Builder
+}
+
 type TerminalState struct {
     // buffer
 	line               []byte
@@ -27,14 +38,27 @@ type TerminalState struct {
 
     commandBeingWritten bool
     scriptBeingWritten bool
+    // in the repl every line that isn't a command is evaluated as tengo, so characters go to the line instead of bindings
+    repl               bool
 
 	onPlaybackBeingSet bool
 	bindChar           rune
+    // hookEvent is the event the next script is attached to, with hookIdle for how long an idle hook waits
+    hookEvent          string
+    hookIdle           time.Duration
+
+    // count is typed as digits before a binding, eg 5j, and is available to the binding's script while it runs
+    count              int
+    bindingCount       int
 }
 
 type Terminal struct {
     inWin *gnc.Window
-    outWin *gnc.Window
+    out *windowwriter.WindowWriter
+    inputHidden bool
+    palette *theme.Palette
+    // capture is set while output is being collected instead of printed
+    capture *capture
 
     State           TerminalState
     onNoPlayback OptionalScript
@@ -44,10 +68,11 @@ type Terminal struct {
     AliasMap        map[string]string
 }
 
-func New(inwin, outwin *gnc.Window) Terminal {
+func New(inwin, outwin *gnc.Window, palette *theme.Palette) Terminal {
     return Terminal{
         inWin: inwin,
-        outWin: outwin,
+        out: windowwriter.New(outwin),
+        palette: palette,
 
         State: TerminalState{
             line: []byte{},
@@ -95,6 +120,23 @@ func (term *Terminal) NextScriptIsNoPlayback() bool {
     return term.State.onPlaybackBeingSet
 }
 
+func (term *Terminal) NextScriptIsHook() bool {
+    return term.State.hookEvent != ""
+}
+
+func (term *Terminal) SetNextHook(event string, idle time.Duration) {
+    term.State.hookEvent = event
+    term.State.hookIdle = idle
+}
+
+// TakeHook returns the event the next script is being attached to and stops attaching scripts to it
+func (term *Terminal) TakeHook() (string, time.Duration) {
+    event, idle := term.State.hookEvent, term.State.hookIdle
+    term.State.hookEvent = ""
+    term.State.hookIdle = 0
+    return event, idle
+}
+
 func (term *Terminal) BindCurrentToScript(s script.Script) {
     term.BindMap[term.State.bindChar] = s
     term.State.bindChar = 0
@@ -137,6 +179,14 @@ func (term Terminal) ScriptBeingWritten() bool {
     return term.State.scriptBeingWritten
 }
 
+func (term *Terminal) SetRepl(on bool) {
+    term.State.repl = on
+}
+
+func (term Terminal) InRepl() bool {
+    return term.State.repl
+}
+
 func (term *Terminal) EndScript() {
     term.State.scriptBeingWritten = false
     term.ClearLine()
@@ -154,27 +204,91 @@ func (term *Terminal) BindNextScriptToNoPlayback() {
     term.State.onPlaybackBeingSet = true
 }
 
+// PendingState is the part of the state that waits on later input, such as a script being written and what it will be bound or attached to
+type PendingState struct {
+    scriptBeingWritten bool
+    repl               bool
+    onPlaybackBeingSet bool
+    bindChar           rune
+    hookEvent          string
+    hookIdle           time.Duration
+    lines              string
+}
+
+func (term Terminal) Pending() PendingState {
+    return PendingState{
+        scriptBeingWritten: term.State.scriptBeingWritten,
+        repl:               term.State.repl,
+        onPlaybackBeingSet: term.State.onPlaybackBeingSet,
+        bindChar:           term.State.bindChar,
+        hookEvent:          term.State.hookEvent,
+        hookIdle:           term.State.hookIdle,
+        lines:              string(term.State.lines),
+    }
+}
+
+// RestorePending puts back the pending state, eg after a command run by a script tried to change it
+func (term *Terminal) RestorePending(p PendingState) {
+    term.State.scriptBeingWritten = p.scriptBeingWritten
+    term.State.repl = p.repl
+    term.State.onPlaybackBeingSet = p.onPlaybackBeingSet
+    term.State.bindChar = p.bindChar
+    term.State.hookEvent = p.hookEvent
+    term.State.hookIdle = p.hookIdle
+    term.State.lines = []byte(p.lines)
+}
+
+// RunBinding runs the script bound to ch
+// unbound digits are collected into a count for the next binding instead, as long as the count doesn't start with 0
 func (term *Terminal) RunBinding(ch rune) {
     if script, ok := term.BindMap[ch]; ok {
+        term.State.bindingCount = term.State.count
+        term.State.count = 0
         term.RunScript(script)
+        term.State.bindingCount = 0
+    } else if ch >= '0' && ch <= '9' && (ch != '0' || term.State.count > 0) {
+        if term.State.count < maxCount {
+            term.State.count = term.State.count*10 + int(ch-'0')
+        }
     } else {
+        term.State.count = 0
         term.InfoPrintf("%c is not bound.\n", ch)
     }
 }
 
+// maxCount stops counts from overflowing, since nothing useful needs a count that large
+const maxCount = 100000
+
+// Count returns the count typed before the binding being run, or 1 if there wasn't one
+func (term *Terminal) Count() int {
+    if term.State.bindingCount > 0 {
+        return term.State.bindingCount
+    }
+    return 1
+}
+
 func (term *Terminal) RunScript(s script.Script) {
     term.InfoPrintln("Running script: " + s.Name())
 
     defer term.InfoPrintRuntimeError()
     if err := s.Run(); err != nil {
-        term.InfoPrintln(err)
+        term.ErrorPrintln(err)
+    }
+}
+
+// RunScriptQuietly runs a script without saying so, for scripts like hooks that run often enough for it to be noise
+// errors are still printed
+func (term *Terminal) RunScriptQuietly(s script.Script) {
+    defer term.InfoPrintRuntimeError()
+    if err := s.Run(); err != nil {
+        term.ErrorPrintln(err)
     }
 }
 
 func (term *Terminal) InputCharacter(ch rune) {
     term.UpdateCommandBeingWritten(ch)
 
-    if !term.CommandBeingWritten() && !term.ScriptBeingWritten() && validBinding(ch) {
+    if !term.CommandBeingWritten() && !term.ScriptBeingWritten() && !term.InRepl() && validBinding(ch) {
         term.RunBinding(ch) 
     } else if ch == 263 {
         term.handleBackspace()
@@ -209,33 +323,87 @@ func (term *Terminal) UpdateCommandBeingWritten(ch rune) {
 }
 
 func (term *Terminal) updateInput() {
-	replaceCurrentLine(term.inWin, term.State.line)
+    if !term.inputHidden {
+        replaceCurrentLine(term.inWin, term.State.line)
+    }
+}
+
+// SetHidden stops the input and output windows from being drawn while their panes aren't shown
+func (term *Terminal) SetHidden(input, output bool) {
+    term.inputHidden = input
+    term.out.SetHidden(output)
 }
 
 func (c Terminal) InfoPrint(args ...interface{}) {
-    c.outWin.Print(args...)
-    c.outWin.Refresh()
+    c.writeInfo(fmt.Sprint(args...))
 }
 
 func (c Terminal) InfoPrintln(args ...interface{}) {
-    c.outWin.Println(args...)
-    c.outWin.Refresh()
+    c.writeInfo(fmt.Sprintln(args...))
 }
 
 func (c Terminal) InfoPrintf(format string, args ...interface{}) {
-    c.outWin.Printf(format, args...)
-    c.outWin.Refresh()
+    c.writeInfo(fmt.Sprintf(format, args...))
+}
+
+// ErrorPrint, ErrorPrintln and ErrorPrintf are like their Info counterparts but use the theme's error style
+func (c Terminal) ErrorPrint(args ...interface{}) {
+    c.writeError(fmt.Sprint(args...))
+}
+
+func (c Terminal) ErrorPrintln(args ...interface{}) {
+    c.writeError(fmt.Sprintln(args...))
+}
+
+func (c Terminal) ErrorPrintf(format string, args ...interface{}) {
+    c.writeError(fmt.Sprintf(format, args...))
+}
+
+func (c Terminal) writeInfo(s string) {
+    if c.capture != nil {
+        c.capture.output.WriteString(s)
+    } else {
+        c.out.WriteString(s)
+    }
+}
+
+func (c Terminal) writeError(s string) {
+    if c.capture != nil {
+        c.capture.errors.WriteString(s)
+    } else {
+        c.out.WriteStyled(s, c.palette.Attributes(theme.Error))
+    }
+}
+
+// Capture runs f with what it prints collected instead of shown, returning the output and the errors separately
+// captures can be nested, in which case only the innermost collects anything
+func (term *Terminal) Capture(f func()) (string, string) {
+    previous := term.capture
+    c := &capture{}
+    term.capture = c
+    defer func() { term.capture = previous }()
+    f()
+    return c.output.String(), c.errors.String()
+}
+
+// Redraw prints the output history and the line being entered again, eg after the windows were resized
+func (term *Terminal) Redraw() {
+    term.out.Redraw()
+    if !term.inputHidden {
+        term.inWin.Erase()
+        term.updateInput()
+    }
 }
 
 func (c Terminal) InfoPrintRuntimeError() {
 	if runtimeError := recover(); runtimeError != nil {
-		c.InfoPrint(fmt.Sprintf("\nRuntime Error: %s\n", runtimeError))
+		c.ErrorPrint(fmt.Sprintf("\nRuntime Error: %s\n", runtimeError))
 	}
 }
 
 func (c Terminal) RequireArgCount(args []string, count int) bool {
     if len(args) != count {
-        c.InfoPrintf("Command Error: %s takes %d arguments but recieved %d.\n", args[0], count, len(args))
+        c.ErrorPrintf("Command Error: %s takes %d arguments but recieved %d.\n", args[0], count, len(args))
         return false
     }
     return true
@@ -243,7 +411,7 @@ func (c Terminal) RequireArgCount(args []string, count int) bool {
 
 func (c Terminal) RequireArgCountGTE(args []string, count int) bool {
     if len(args) < count {
-        c.InfoPrintf("Command Error: %s takes %d or more arguments but recieved %d.\n", args[0], count, len(args))
+        c.ErrorPrintf("Command Error: %s takes %d or more arguments but recieved %d.\n", args[0], count, len(args))
         return false
     }
     return true
@@ -259,9 +427,11 @@ func validBinding(ch rune) bool {
     return true
 }
 
+// pop removes the last character, which may be more than one byte
 func pop(bytes []byte) []byte {
     if len(bytes) >= 1 {
-        return bytes[:len(bytes)-1]
+        _, size := utf8.DecodeLastRune(bytes)
+        return bytes[:len(bytes)-size]
     }
     return bytes
 }
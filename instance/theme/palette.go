@@ -0,0 +1,110 @@
+package theme
+
+import (
+	gnc "github.com/rthornton128/goncurses"
+
+	"errors"
+	"fmt"
+)
+
+// Palette turns the styles of a theme into ncurses attributes
+// on terminals without color support only the attributes of each style are used
+type Palette struct {
+	colors bool
+	// count is how many colors the terminal has, so colors numbered from count up can't be used
+	count int
+	// defaults is whether the terminal's own colors can be used, otherwise white on black is used in their place
+	defaults bool
+	theme    Theme
+}
+
+// NewPalette starts color mode if the terminal supports it and applies the default theme
+func NewPalette() *Palette {
+	p := &Palette{}
+	if gnc.HasColors() && gnc.StartColor() == nil {
+		p.colors = true
+		p.count = gnc.Colors()
+		p.defaults = gnc.UseDefaultColors() == nil
+	}
+	p.SetTheme(Builtin["default"])
+	return p
+}
+
+// SetTheme replaces the styles of every element, returning the first error from setting their colors
+func (p *Palette) SetTheme(t Theme) error {
+	p.theme = t.Copy()
+	var first error
+	for _, element := range Elements {
+		if err := p.apply(element); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// SetStyle replaces the style of a single element, keeping the old one if the terminal can't show the new one's colors
+func (p *Palette) SetStyle(element Element, s Style) error {
+	for _, color := range []int16{s.Foreground, s.Background} {
+		if p.colors && color != DefaultColor && int(color) >= p.count {
+			return errors.New(fmt.Sprintf("theme: the terminal has %d colors, so color %d can't be used.", p.count, color))
+		}
+	}
+	old := p.theme[element]
+	p.theme[element] = s
+	if err := p.apply(element); err != nil {
+		p.theme[element] = old
+		p.apply(element)
+		return err
+	}
+	return nil
+}
+
+func (p *Palette) apply(element Element) error {
+	if !p.colors {
+		return nil
+	}
+	s := p.theme[element]
+	fg, bg := s.Foreground, s.Background
+	if !p.defaults {
+		if fg == DefaultColor {
+			fg = gnc.C_WHITE
+		}
+		if bg == DefaultColor {
+			bg = gnc.C_BLACK
+		}
+	}
+	if err := gnc.InitPair(pair(element), fg, bg); err != nil {
+		return errors.New(fmt.Sprintf("theme: the colors of %s couldn't be set: %v.", element, err))
+	}
+	return nil
+}
+
+// Attributes returns the attributes to draw an element with, including its color pair when colors are available
+// standout and reverse swap the pair's colors, so themes only use them where they don't set colors themselves
+func (p *Palette) Attributes(element Element) gnc.Char {
+	if p == nil {
+		return gnc.A_NORMAL
+	}
+	s := p.theme[element]
+	attributes := s.Attributes
+	if p.colors {
+		attributes |= gnc.ColorPair(pair(element))
+	} else if s.Background != DefaultColor {
+		// a background color is what sets the element apart, so reverse it instead of losing it
+		attributes |= gnc.A_REVERSE
+	}
+	return attributes
+}
+
+func (p *Palette) HasColors() bool {
+	return p != nil && p.colors
+}
+
+func pair(element Element) int16 {
+	for i, e := range Elements {
+		if e == element {
+			return int16(i + 1)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,161 @@
+package theme
+
+import (
+	gnc "github.com/rthornton128/goncurses"
+
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Element string
+
+const (
+	Directory Element = "directory"
+	Song      Element = "song"
+	Selection Element = "selection"
+	Playing   Element = "playing"
+	Match     Element = "match"
+	Marked    Element = "marked"
+	Border    Element = "border"
+	Error     Element = "error"
+)
+
+// Elements lists every styled element; an element's color pair is its position in the list plus one
+var Elements = []Element{Directory, Song, Selection, Playing, Match, Marked, Border, Error}
+
+// DefaultColor is the terminal's own foreground or background color
+const DefaultColor int16 = -1
+
+// Style is how an element is drawn
+// the attributes are used even without color support, so they should be enough to tell elements apart on their own
+type Style struct {
+	Foreground int16
+	Background int16
+	Attributes gnc.Char
+}
+
+type Theme map[Element]Style
+
+func style(fg, bg int16, attributes gnc.Char) Style {
+	return Style{Foreground: fg, Background: bg, Attributes: attributes}
+}
+
+// Builtin holds the themes that can be selected by name
+var Builtin = map[string]Theme{
+	"default": {
+		Directory: style(DefaultColor, DefaultColor, gnc.A_BOLD),
+		Song:      style(DefaultColor, DefaultColor, gnc.A_NORMAL),
+		Selection: style(DefaultColor, DefaultColor, gnc.A_REVERSE),
+		Playing:   style(DefaultColor, DefaultColor, gnc.A_BOLD|gnc.A_UNDERLINE),
+		Match:     style(DefaultColor, DefaultColor, gnc.A_UNDERLINE),
+		Marked:    style(DefaultColor, DefaultColor, gnc.A_BOLD),
+		Border:    style(DefaultColor, DefaultColor, gnc.A_NORMAL),
+		Error:     style(DefaultColor, DefaultColor, gnc.A_BOLD),
+	},
+	"ocean": {
+		Directory: style(gnc.C_BLUE, DefaultColor, gnc.A_BOLD),
+		Song:      style(gnc.C_CYAN, DefaultColor, gnc.A_NORMAL),
+		Selection: style(gnc.C_BLACK, gnc.C_CYAN, gnc.A_NORMAL),
+		Playing:   style(gnc.C_GREEN, DefaultColor, gnc.A_BOLD|gnc.A_UNDERLINE),
+		Match:     style(gnc.C_YELLOW, DefaultColor, gnc.A_UNDERLINE),
+		Marked:    style(gnc.C_MAGENTA, DefaultColor, gnc.A_BOLD),
+		Border:    style(gnc.C_BLUE, DefaultColor, gnc.A_NORMAL),
+		Error:     style(gnc.C_RED, DefaultColor, gnc.A_BOLD),
+	},
+	"ember": {
+		Directory: style(gnc.C_YELLOW, DefaultColor, gnc.A_BOLD),
+		Song:      style(DefaultColor, DefaultColor, gnc.A_NORMAL),
+		Selection: style(gnc.C_BLACK, gnc.C_YELLOW, gnc.A_NORMAL),
+		Playing:   style(gnc.C_RED, DefaultColor, gnc.A_BOLD|gnc.A_UNDERLINE),
+		Match:     style(gnc.C_MAGENTA, DefaultColor, gnc.A_UNDERLINE),
+		Marked:    style(gnc.C_CYAN, DefaultColor, gnc.A_BOLD),
+		Border:    style(gnc.C_RED, DefaultColor, gnc.A_NORMAL),
+		Error:     style(gnc.C_RED, DefaultColor, gnc.A_BOLD),
+	},
+	"forest": {
+		Directory: style(gnc.C_GREEN, DefaultColor, gnc.A_BOLD),
+		Song:      style(gnc.C_WHITE, DefaultColor, gnc.A_NORMAL),
+		Selection: style(gnc.C_BLACK, gnc.C_GREEN, gnc.A_NORMAL),
+		Playing:   style(gnc.C_YELLOW, DefaultColor, gnc.A_BOLD|gnc.A_UNDERLINE),
+		Match:     style(gnc.C_CYAN, DefaultColor, gnc.A_UNDERLINE),
+		Marked:    style(gnc.C_MAGENTA, DefaultColor, gnc.A_BOLD),
+		Border:    style(gnc.C_GREEN, DefaultColor, gnc.A_NORMAL),
+		Error:     style(gnc.C_RED, DefaultColor, gnc.A_BOLD),
+	},
+}
+
+// BuiltinNames returns the names of the builtin themes in alphabetical order
+func BuiltinNames() []string {
+	names := []string{}
+	for name := range Builtin {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (t Theme) Copy() Theme {
+	result := make(Theme)
+	for element, s := range t {
+		result[element] = s
+	}
+	return result
+}
+
+var colorNames = map[string]int16{
+	"default": DefaultColor,
+	"black":   gnc.C_BLACK,
+	"red":     gnc.C_RED,
+	"green":   gnc.C_GREEN,
+	"yellow":  gnc.C_YELLOW,
+	"blue":    gnc.C_BLUE,
+	"magenta": gnc.C_MAGENTA,
+	"cyan":    gnc.C_CYAN,
+	"white":   gnc.C_WHITE,
+}
+
+var attributeNames = map[string]gnc.Char{
+	"normal":    gnc.A_NORMAL,
+	"bold":      gnc.A_BOLD,
+	"dim":       gnc.A_DIM,
+	"underline": gnc.A_UNDERLINE,
+	"reverse":   gnc.A_REVERSE,
+	"standout":  gnc.A_STANDOUT,
+	"blink":     gnc.A_BLINK,
+}
+
+func ParseElement(name string) (Element, error) {
+	for _, element := range Elements {
+		if string(element) == name {
+			return element, nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf("theme: '%s' is not an element; the elements are directory, song, selection, playing, match, marked, border and error.", name))
+}
+
+// ParseColor accepts a color name or a color number from 0 to 255
+func ParseColor(name string) (int16, error) {
+	if color, ok := colorNames[name]; ok {
+		return color, nil
+	}
+	if number, err := strconv.Atoi(name); err == nil && number >= 0 && number < 256 {
+		return int16(number), nil
+	}
+	return 0, errors.New(fmt.Sprintf("theme: '%s' is not a color name or a number from 0 to 255.", name))
+}
+
+// ParseAttributes accepts a comma separated list of attribute names, eg bold,underline
+func ParseAttributes(list string) (gnc.Char, error) {
+	var attributes gnc.Char = gnc.A_NORMAL
+	for _, name := range strings.Split(list, ",") {
+		attribute, ok := attributeNames[name]
+		if !ok {
+			return 0, errors.New(fmt.Sprintf("theme: '%s' is not an attribute; the attributes are normal, bold, dim, underline, reverse, standout and blink.", name))
+		}
+		attributes |= attribute
+	}
+	return attributes, nil
+}
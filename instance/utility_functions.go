@@ -3,7 +3,6 @@ package instance
 import (
 	"github.com/StructsNotClasses/mim/instance/playback"
 	"github.com/StructsNotClasses/mim/remote"
-	"github.com/StructsNotClasses/mim/windowwriter"
 
 	gnc "github.com/rthornton128/goncurses"
 
@@ -22,7 +21,7 @@ func windowPrintRuntimeError(outputWindow *gnc.Window) {
 
 // playFileWithMplayer runs the command "mplayer -slave -vo null <file>" and notifies upon the beginning and end of playback to notifier
 // the remote returned contains a pipe to the commands stdin and can be used to send it input
-func playFileWithMplayer(file string, notifier chan playback.Notification, out windowwriter.WindowWriter) remote.Remote {
+func playFileWithMplayer(file string, notifier chan playback.Notification, out io.WriteCloser) remote.Remote {
 	cmd := exec.Command("mplayer",
 		"-slave", "-vo", "null", "-quiet", file)
 
@@ -33,7 +32,7 @@ func playFileWithMplayer(file string, notifier chan playback.Notification, out w
 
 	go runWithWriter(cmd, out, notifier)
 
-	return remote.Remote{pipe}
+	return remote.Remote{Pipe: pipe}
 }
 
 func runWithWriter(cmd *exec.Cmd, w io.WriteCloser, notifier chan playback.Notification) {
@@ -44,6 +43,7 @@ func runWithWriter(cmd *exec.Cmd, w io.WriteCloser, notifier chan playback.Notif
 	if err != nil {
 		log.Fatal(err)
 	}
+	w.Close()
 
 	notifier <- playback.Ended
 }
@@ -0,0 +1,19 @@
+package main
+
+// goncurses links the narrow ncurses library, which prints multibyte characters as escapes like M-C
+// linking the wide library first makes its symbols the ones used
+
+// #cgo !darwin,!openbsd,!windows pkg-config: ncursesw
+// #include <locale.h>
+// #include <stdlib.h>
+import "C"
+
+import "unsafe"
+
+// setLocale adopts the locale from the environment, which ncurses needs to print multibyte characters such as accents, CJK or emoji
+// it must be called before ncurses starts
+func setLocale() {
+	empty := C.CString("")
+	defer C.free(unsafe.Pointer(empty))
+	C.setlocale(C.LC_ALL, empty)
+}
@@ -12,6 +12,8 @@ func main() {
 	const musicDirectory = "/mnt/music"
 	const configFile = "/home/pugpugpugs/mim/config.mim"
 
+	setLocale()
+
 	// start ncurses
 	backgroundWindow, err := gnc.Init()
 	if err != nil {
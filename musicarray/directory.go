@@ -6,6 +6,8 @@ type Directory struct {
 	PrevDirectoryIndex int
 	EndDirectoryIndex  int
 	ItemCount          int
+	// Virtual directories don't exist on disk, eg smart playlists
+	Virtual bool
 }
 
 func (d Directory) Expanded() bool {
@@ -12,6 +12,8 @@ type Entry struct {
 	Name  string
 	Path  string
 	Depth int
-	Dir   Directory
-	Song  Song
+	// ParentIndex is the index of the directory containing the entry or -1 for the root
+	ParentIndex int
+	Dir         Directory
+	Song        Song
 }
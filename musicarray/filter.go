@@ -0,0 +1,36 @@
+package musicarray
+
+// Keep returns a new array with only the songs keep returns true for, along with the root and every directory containing one of them
+// directories left without songs are dropped, and item counts and directory indices are rebuilt
+func (arr MusicArray) Keep(keep func(e Entry) bool) MusicArray {
+	if len(arr) == 0 {
+		return arr
+	}
+	kept := make([]bool, len(arr))
+	kept[0] = true
+	for i, e := range arr {
+		if e.Type == SongEntry && keep(e) {
+			for p := i; p != -1 && !kept[p]; p = arr[p].ParentIndex {
+				kept[p] = true
+			}
+		}
+	}
+
+	// newIndex maps indices in arr to indices in the result, for counting each directory's items
+	newIndex := make([]int, len(arr))
+	result := MusicArray{}
+	for i, e := range arr {
+		if !kept[i] {
+			continue
+		}
+		newIndex[i] = len(result)
+		if e.Type == DirectoryEntry {
+			e.Dir.ItemCount = 0
+		}
+		if e.ParentIndex != -1 {
+			result[newIndex[e.ParentIndex]].Dir.ItemCount++
+		}
+		result = append(result, e)
+	}
+	return rebuildDirectoryIndices(result)
+}
@@ -1,6 +1,8 @@
 package musicarray
 
 import (
+	"github.com/StructsNotClasses/mim/tags"
+
 	"errors"
 	"fmt"
 	"io/fs"
@@ -24,15 +26,16 @@ var fileRules = []string {
 }
 
 
-func New(rootPath string) (MusicArray, error) {
-	arr, err := directoryToArray(rootPath, 0)
+// New reads every song under rootPath, naming entries with the pipeline
+func New(rootPath string, naming NamePipeline) (MusicArray, error) {
+	arr, err := directoryToArray(rootPath, 0, naming)
 	if err != nil {
 		return arr, err
 	}
 	return addDirectoryIndices(arr), nil
 }
 
-func directoryToArray(root string, depth int) (MusicArray, error) {
+func directoryToArray(root string, depth int, naming NamePipeline) (MusicArray, error) {
 	var arr MusicArray
 
 	entries, err := fs.ReadDir(os.DirFS(root), ".")
@@ -42,7 +45,7 @@ func directoryToArray(root string, depth int) (MusicArray, error) {
 
 	arr = append(arr, Entry{
 		Type:  DirectoryEntry,
-		Name:  formatDirectoryIfValid(filepath.Base(root)),
+		Name:  naming.Format(filepath.Base(root), true),
 		Path:  root,
 		Depth: depth,
 		// previous and next directory indices will be properly initialized later
@@ -57,7 +60,7 @@ func directoryToArray(root string, depth int) (MusicArray, error) {
 	// add subdirectories in lexical order
 	for _, entry := range entries {
 		if entry.IsDir() {
-			subdirArray, err := directoryToArray(root+"/"+entry.Name(), depth+1)
+			subdirArray, err := directoryToArray(root+"/"+entry.Name(), depth+1, naming)
 			if err != nil {
 				return arr, err
 			} else {
@@ -78,12 +81,13 @@ func directoryToArray(root string, depth int) (MusicArray, error) {
                 } 
             }
             if passes {
+                path := root + "/" + entry.Name()
                 arr = append(arr, Entry{
                     Type:  SongEntry,
-                    Name:  formatIfValid(entry.Name()),
-                    Path:  root + "/" + entry.Name(),
+                    Name:  naming.Format(entry.Name(), false),
+                    Path:  path,
                     Depth: depth + 1,
-                    Song:  Song{},
+                    Song:  readSong(path, entry),
                 })
             } else {
                 arr[containing].Dir.ItemCount--
@@ -94,102 +98,29 @@ func directoryToArray(root string, depth int) (MusicArray, error) {
 	return arr, nil
 }
 
-func formatDirectoryIfValid(name string) string {
-    if !dirInFormat(name) {
-        return name
-    }
-
-    atScores := strings.Split(name, "_")
-    for i, s := range(atScores) {
-        atScores[i] = capitalizeFirst(s)
+// readSong collects the tags and file information of a song
+// files with unreadable or missing tags are still valid songs, they simply have no tags
+func readSong(path string, entry fs.DirEntry) Song {
+    info, _ := tags.Read(path)
+    song := Song{
+        Tags:       info.Fields,
+        Duration:   info.Duration,
+        SampleRate: info.SampleRate,
+        Bitrate:    info.Bitrate,
+        Stats:      &Stats{},
     }
-    return strings.Join(atScores, " ")
-}
-
-func dirInFormat(s string) bool {
-    // checks if the filename is only lowercase, digits, and underscores
-    // eg 10-foo_bar.mp3
-    for _, r := range(s) {
-        if !isLower(r) && !isDigit(r) && r != rune('_') {
-            // invalid character for formatting
-            return false
-        }
-    }
-    return true
-}
-
-func formatIfValid(filename string) string {
-    if !inFormat(filename) {
-        return filename
-    }
-
-    // capitalize after the hyphen and replace "-" with " - " for readability
-    atHyphens := strings.Split(filename, "-")
-    for i, s := range(atHyphens) {
-        atHyphens[i] = capitalizeFirst(s)
-    }
-    res := strings.Join(atHyphens, " - ")
-
-    // capitalize after underscores and replace underscores with spaces
-    atScores := strings.Split(res, "_")
-    for i, s := range(atScores) {
-        atScores[i] = capitalizeFirst(s)
-    }
-    res = strings.Join(atScores, " ")
-
-    //remove file extension
-    return strings.Split(res, ".")[0]
-}
-
-func inFormat(s string) bool {
-    // following code basically checks if the filename is only lowercase, digits, and underscores
-    // eg 10-foo_bar.mp3
-    // only one hyphen is allowed, which should be used to denote number within an album
-    periodCount := 0
-    hyphenCount := 0
-    for _, r := range(s) {
-        if !isLower(r) && !isDigit(r) && r != rune('_') {
-            if r == rune('.') {
-                if periodCount == 1 {
-                    return false
-                } else {
-                    periodCount++
-                }
-            } else if r == rune('-') {
-                if hyphenCount == 1{
-                    return false
-                } else {
-                    hyphenCount++
-                }
-            } else {
-                // invalid character for formatting
-                return false
-            }
-        }
-    }
-    return true
-}
-
-func isLower(r rune) bool {
-    return r <= rune('z') && r >= rune('a')
-}
-
-func isDigit(r rune) bool {
-    return r <= rune('9') && r >= rune('0')
-}
-
-func capitalizeFirst(s string) string {
-    if len(s) == 0 {
-        return s
-    } else if len(s) == 1 {
-        return strings.ToUpper(s)
-    } else {
-        first := s[0:1]
-        return strings.ToUpper(first) + s[1:]
+    if fileInfo, err := entry.Info(); err == nil {
+        song.Size = fileInfo.Size()
+        song.ModTime = fileInfo.ModTime()
     }
+    return song
 }
 
 func addDirectoryIndices(arr MusicArray) MusicArray {
+	if len(arr) == 0 {
+		return arr
+	}
+	arr[0].ParentIndex = -1
 	_, err := arr.buildNextIndices(0)
 	if err != nil {
 		log.Fatal(err)
@@ -210,6 +141,7 @@ func (arr MusicArray) buildNextIndices(targetDirectoryIndex int) (int, error) {
 		if i >= expectedNextIndex {
 			break
 		}
+		arr[i].ParentIndex = targetDirectoryIndex
 		if arr[i].Type == DirectoryEntry {
 			subdirEntryCount, err := arr.buildNextIndices(i)
 			if err != nil {
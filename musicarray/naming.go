@@ -0,0 +1,137 @@
+package musicarray
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// NameRule is one step of turning a file or directory name into the name shown in the tree
+type NameRule func(name string, isDir bool) string
+
+// NamePipeline applies each of its rules in order
+type NamePipeline []NameRule
+
+// Format turns the base name of a file or directory into its display name
+// a pipeline without rules leaves names as they are on disk
+func (p NamePipeline) Format(base string, isDir bool) string {
+	name := base
+	for _, rule := range p {
+		name = rule(name, isDir)
+	}
+	return name
+}
+
+// DefaultNaming strips extensions and prettifies names written like 10-foo_bar.mp3, which is how names were always shown
+func DefaultNaming() NamePipeline {
+	return NamePipeline{StripExtension, Prettify}
+}
+
+// ParseNameRule creates a builtin rule from its name and arguments, as used by the :name_rule command
+func ParseNameRule(name string, args []string) (NameRule, error) {
+	requireArgs := func(count int) error {
+		if len(args) != count {
+			return errors.New(fmt.Sprintf("name_rule: %s takes %d arguments but recieved %d.", name, count, len(args)))
+		}
+		return nil
+	}
+
+	switch name {
+	case "strip_extension":
+		return StripExtension, requireArgs(0)
+	case "strip_track_number":
+		return StripTrackNumber, requireArgs(0)
+	case "title_case":
+		return TitleCase, requireArgs(0)
+	case "prettify":
+		return Prettify, requireArgs(0)
+	case "replace":
+		if err := requireArgs(2); err != nil {
+			return nil, err
+		}
+		return Replace(args[0], args[1])
+	}
+	return nil, errors.New(fmt.Sprintf("name_rule: unknown rule '%s'; the rules are strip_extension, strip_track_number, title_case, prettify, replace and tengo.", name))
+}
+
+// StripExtension removes the extension of songs, leaving any other periods in the name alone
+func StripExtension(name string, isDir bool) string {
+	if isDir {
+		return name
+	}
+	if stripped := strings.TrimSuffix(name, filepath.Ext(name)); stripped != "" {
+		return stripped
+	}
+	return name
+}
+
+// trackNumber matches numbering like "01 - ", "01. ", "1-02 " or "01_" at the start of a song name
+var trackNumber = regexp.MustCompile(`^\d{1,3}([-.]\d{1,3})?(\s*[-._)]\s*|\s+)`)
+
+// StripTrackNumber removes the track number from the start of song names
+func StripTrackNumber(name string, isDir bool) string {
+	if isDir {
+		return name
+	}
+	if stripped := trackNumber.ReplaceAllString(name, ""); strings.TrimSpace(stripped) != "" {
+		return stripped
+	}
+	return name
+}
+
+// TitleCase capitalizes the first letter of every word, where a word starts after anything that isn't a letter, digit or apostrophe
+func TitleCase(name string, isDir bool) string {
+	runes := []rune(name)
+	for i, r := range runes {
+		if i == 0 || !unicode.IsLetter(runes[i-1]) && !unicode.IsDigit(runes[i-1]) && runes[i-1] != '\'' {
+			runes[i] = unicode.ToTitle(r)
+		}
+	}
+	return string(runes)
+}
+
+// Prettify turns names made only of lowercase letters, digits and underscores into spaced and capitalized ones, eg 10-foo_bar becomes 10 - Foo Bar
+// song names may also have a single hyphen separating the track number
+// names in any other format were probably written by hand, so they are left alone
+func Prettify(name string, isDir bool) string {
+	hyphens := 0
+	for _, r := range name {
+		if r == '-' && !isDir && hyphens == 0 {
+			hyphens++
+		} else if !unicode.IsLower(r) && !unicode.IsDigit(r) && r != '_' {
+			return name
+		}
+	}
+
+	parts := strings.Split(name, "-")
+	for i := range parts {
+		parts[i] = TitleCase(strings.ReplaceAll(parts[i], "_", " "), isDir)
+	}
+	return strings.Join(parts, " - ")
+}
+
+// Replace creates a rule replacing every match of a regular expression, where the replacement can refer to groups with $1 and so on
+func Replace(pattern, replacement string) (NameRule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("name_rule: invalid regular expression '%s': %v", pattern, err))
+	}
+	return func(name string, isDir bool) string {
+		return re.ReplaceAllString(name, replacement)
+	}, nil
+}
+
+// Rename formats the names of every entry on disk again, eg after the naming rules changed
+// virtual directories keep their names since they aren't files
+func (arr MusicArray) Rename(format func(base string, isDir bool) string) {
+	for i := range arr {
+		isDir := arr[i].Type == DirectoryEntry
+		if isDir && arr[i].Dir.Virtual {
+			continue
+		}
+		arr[i].Name = format(filepath.Base(arr[i].Path), isDir)
+	}
+}
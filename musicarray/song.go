@@ -1,3 +1,21 @@
 package musicarray
 
-type Song struct{}
+import (
+	"time"
+)
+
+type Song struct {
+	Tags       map[string]string
+	Duration   time.Duration
+	SampleRate int
+	Bitrate    int
+	Size       int64
+	ModTime    time.Time
+	// Stats is shared by every copy of the entry, such as those in virtual directories, so play counts stay consistent
+	Stats *Stats
+}
+
+type Stats struct {
+	PlayCount  int
+	LastPlayed time.Time
+}
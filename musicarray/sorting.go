@@ -0,0 +1,275 @@
+package musicarray
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SortMode is an order for the entries inside a directory
+// directories always come before songs, as they do on disk, and each sort orders both of them
+type SortMode string
+
+const (
+	// SortLexical keeps the order entries were read in, which is by file name for the filesystem and alphabetical for views
+	SortLexical SortMode = "lexical"
+	// SortNatural orders by name, comparing runs of digits by their value so "Track 2" comes before "Track 10"
+	SortNatural SortMode = "natural"
+	// SortTrack orders songs by disc and track number, putting untagged songs last, and directories by name since they have no track number
+	SortTrack SortMode = "track"
+	// SortModified puts the most recently modified first, which is usually the most recently added, where a directory is as recent as its newest song
+	SortModified SortMode = "mtime"
+	// SortSize orders from smallest to largest, where a directory's size is the total of its songs
+	SortSize SortMode = "size"
+	// SortYear orders from oldest to newest by the year tag, where a directory's year is that of its oldest song
+	SortYear SortMode = "year"
+)
+
+var sortModes = []SortMode{SortLexical, SortNatural, SortTrack, SortModified, SortSize, SortYear}
+
+// SortOrder is a sort mode along with whether it is reversed
+type SortOrder struct {
+	Mode    SortMode
+	Reverse bool
+}
+
+func ParseSortMode(name string) (SortMode, error) {
+	for _, mode := range sortModes {
+		if string(mode) == name {
+			return mode, nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf("sort: unknown sort mode '%s'; the modes are lexical, natural, track, mtime, size and year.", name))
+}
+
+// Sort returns a copy of the array with the contents of every directory ordered by order
+// directories with an order in directoryOrders, which is keyed by path, use it instead, as do the directories inside them without their own
+// directory indices are built before sorting, so arrays that don't have them yet such as a VirtualDirectory can be sorted, and rebuilt for the new order
+func (arr MusicArray) Sort(order SortOrder, directoryOrders map[string]SortOrder) MusicArray {
+	if len(arr) == 0 {
+		return arr
+	}
+	arr = rebuildDirectoryIndices(append(MusicArray{}, arr...))
+	return rebuildDirectoryIndices(arr.sortDirectory(0, order, directoryOrders))
+}
+
+// sortDirectory returns the directory at index followed by its sorted contents
+func (arr MusicArray) sortDirectory(index int, order SortOrder, directoryOrders map[string]SortOrder) MusicArray {
+	end := arr[index].Dir.EndDirectoryIndex
+	if own, ok := directoryOrders[arr[index].Path]; ok {
+		order = own
+	}
+
+	// each child is either a song or a whole directory along with its contents
+	children := []MusicArray{}
+	for i := index + 1; i < end; {
+		if arr[i].Type == DirectoryEntry {
+			children = append(children, arr.sortDirectory(i, order, directoryOrders))
+			i = arr[i].Dir.EndDirectoryIndex
+		} else {
+			children = append(children, arr[i:i+1])
+			i++
+		}
+	}
+
+	if order.Mode != SortLexical {
+		keys := make([]sortKey, len(children))
+		for n, child := range children {
+			keys[n] = child.sortKey()
+		}
+		sort.Stable(byKey{children, keys, order})
+	} else if order.Reverse {
+		for a, b := 0, len(children)-1; a < b; a, b = a+1, b-1 {
+			children[a], children[b] = children[b], children[a]
+		}
+		// directories stay first whichever way the order goes
+		sort.SliceStable(children, func(a, b int) bool {
+			return children[a][0].Type == DirectoryEntry && children[b][0].Type != DirectoryEntry
+		})
+	}
+
+	result := MusicArray{arr[index]}
+	for _, child := range children {
+		result = append(result, child...)
+	}
+	return result
+}
+
+// sortKey holds every value a child can be sorted by, where the child is a song or a directory followed by its contents
+type sortKey struct {
+	isDir    bool
+	name     string
+	disc     int
+	track    int
+	modified time.Time
+	size     int64
+	// year is 0 when unknown
+	year int
+}
+
+func (child MusicArray) sortKey() sortKey {
+	first := child[0]
+	key := sortKey{
+		isDir: first.Type == DirectoryEntry,
+		name:  first.Name,
+	}
+	if !key.isDir {
+		key.disc = leadingNumber(first.Song.Tags["discnumber"])
+		key.track = leadingNumber(first.Song.Tags["tracknumber"])
+	}
+	for _, e := range child {
+		if e.Type != SongEntry {
+			continue
+		}
+		if e.Song.ModTime.After(key.modified) {
+			key.modified = e.Song.ModTime
+		}
+		key.size += e.Song.Size
+		if year := leadingNumber(e.Song.Tags["date"]); year > 0 && (key.year == 0 || year < key.year) {
+			key.year = year
+		}
+	}
+	return key
+}
+
+// leadingNumber returns the number at the start of a tag, eg 3 for a track number of "3/12", or 0 if there isn't one
+func leadingNumber(s string) int {
+	digits := strings.TrimSpace(s)
+	end := 0
+	for end < len(digits) && digits[end] >= '0' && digits[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(digits[:end])
+	return n
+}
+
+type byKey struct {
+	children []MusicArray
+	keys     []sortKey
+	order    SortOrder
+}
+
+func (b byKey) Len() int {
+	return len(b.children)
+}
+
+func (b byKey) Swap(i, j int) {
+	b.children[i], b.children[j] = b.children[j], b.children[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+func (b byKey) Less(i, j int) bool {
+	x, y := b.keys[i], b.keys[j]
+	if x.isDir != y.isDir {
+		// directories stay first whichever way the order goes
+		return x.isDir
+	}
+	if b.order.Reverse {
+		x, y = y, x
+	}
+	if c := compareKeys(x, y, b.order.Mode); c != 0 {
+		return c < 0
+	}
+	return NaturalLess(x.name, y.name)
+}
+
+// compareKeys returns a negative number if x sorts before y in the mode, a positive one if it sorts after and 0 if the mode doesn't tell them apart
+func compareKeys(x, y sortKey, mode SortMode) int {
+	switch mode {
+	case SortTrack:
+		// untagged songs go after tagged ones
+		if (x.track == 0) != (y.track == 0) {
+			return compareBools(x.track == 0, y.track == 0)
+		}
+		if x.disc != y.disc {
+			return x.disc - y.disc
+		}
+		return x.track - y.track
+	case SortModified:
+		// newest first
+		if x.modified.After(y.modified) {
+			return -1
+		} else if x.modified.Before(y.modified) {
+			return 1
+		}
+	case SortSize:
+		if x.size < y.size {
+			return -1
+		} else if x.size > y.size {
+			return 1
+		}
+	case SortYear:
+		// entries without a year go last
+		if (x.year == 0) != (y.year == 0) {
+			return compareBools(x.year == 0, y.year == 0)
+		}
+		return x.year - y.year
+	}
+	return 0
+}
+
+func compareBools(x, y bool) int {
+	if x == y {
+		return 0
+	} else if !x {
+		return -1
+	}
+	return 1
+}
+
+// NaturalLess compares names ignoring case, with runs of digits compared by their value so "Track 2" comes before "Track 10"
+func NaturalLess(a, b string) bool {
+	x, y := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	for len(x) > 0 && len(y) > 0 {
+		if unicode.IsDigit(x[0]) && unicode.IsDigit(y[0]) {
+			xDigits, yDigits := digitRun(x), digitRun(y)
+			if c := compareNumbers(x[:xDigits], y[:yDigits]); c != 0 {
+				return c < 0
+			}
+			x, y = x[xDigits:], y[yDigits:]
+			continue
+		}
+		if x[0] != y[0] {
+			return x[0] < y[0]
+		}
+		x, y = x[1:], y[1:]
+	}
+	if len(x) != len(y) {
+		return len(x) < len(y)
+	}
+	// names differing only in case or leading zeros still need a consistent order
+	return a < b
+}
+
+func digitRun(s []rune) int {
+	n := 0
+	for n < len(s) && unicode.IsDigit(s[n]) {
+		n++
+	}
+	return n
+}
+
+// compareNumbers compares two runs of digits by value without converting them, so runs too long for an int still work
+func compareNumbers(x, y []rune) int {
+	x, y = trimZeros(x), trimZeros(y)
+	if len(x) != len(y) {
+		return len(x) - len(y)
+	}
+	for n := range x {
+		if x[n] != y[n] {
+			return int(x[n]) - int(y[n])
+		}
+	}
+	return 0
+}
+
+func trimZeros(digits []rune) []rune {
+	for len(digits) > 1 && digits[0] == '0' {
+		digits = digits[1:]
+	}
+	return digits
+}
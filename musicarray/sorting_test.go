@@ -0,0 +1,86 @@
+package musicarray
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		less bool
+	}{
+		{"Track 2", "Track 10", true},
+		{"Track 10", "Track 2", false},
+		{"track 2", "Track 10", true},
+		{"a", "B", true},
+		{"B", "a", false},
+		{"abc", "abcd", true},
+		{"2", "a", true},
+		{"x", "x", false},
+		// leading zeros don't change the value, and only decide the order when nothing else does
+		{"Track 02", "Track 10", true},
+		{"Track 007", "Track 7", true},
+		{"Track 7", "Track 007", false},
+		{"Track 007 b", "Track 7 a", false},
+		{"0", "00", true},
+		// runs of digits too long for an int
+		{"99999999999999999999999", "100000000000000000000000", true},
+		{"123456789012345678901234 b", "123456789012345678901234 a", false},
+		{"disc 1 track 10", "disc 2 track 1", true},
+		{"disc 1 track 10", "disc 1 track 9", false},
+		// names differing only in case still have an order
+		{"ABC", "abc", true},
+		{"abc", "ABC", false},
+		{"", "a", true},
+		{"a", "", false},
+	}
+
+	for _, test := range tests {
+		if got := NaturalLess(test.a, test.b); got != test.less {
+			t.Errorf("NaturalLess(%q, %q) = %v, want %v", test.a, test.b, got, test.less)
+		}
+	}
+}
+
+func TestNaturalLessSorts(t *testing.T) {
+	want := []string{"01 intro", "1 intro", "2 song", "10 song", "Album", "album 2", "Album 10", "album 10b", "b"}
+	names := []string{"b", "Album 10", "10 song", "album 2", "1 intro", "album 10b", "Album", "2 song", "01 intro"}
+	sort.Slice(names, func(a, b int) bool {
+		return NaturalLess(names[a], names[b])
+	})
+	for n := range want {
+		if names[n] != want[n] {
+			t.Fatalf("got %q, want %q", names, want)
+		}
+	}
+	for _, a := range want {
+		for _, b := range want {
+			if a != b && NaturalLess(a, b) == NaturalLess(b, a) {
+				t.Errorf("NaturalLess(%q, %q) and NaturalLess(%q, %q) agree", a, b, b, a)
+			}
+		}
+	}
+}
+
+func TestSortVirtualDirectory(t *testing.T) {
+	// VirtualDirectory doesn't build directory indices, which Sort has to do itself
+	arr := VirtualDirectory("Playlist", "/playlist", 1, MusicArray{
+		song("track 10", nil),
+		song("track 2", nil),
+		song("Track 1", nil),
+	})
+	sorted := arr.Sort(SortOrder{Mode: SortNatural}, nil)
+	want := []string{"Playlist", "Track 1", "track 2", "track 10"}
+	for n, name := range want {
+		if sorted[n].Name != name {
+			t.Errorf("entry %d: got %s, want %s", n, sorted[n].Name, name)
+		}
+	}
+	if sorted[0].Dir.EndDirectoryIndex != len(want) {
+		t.Errorf("got end index %d, want %d", sorted[0].Dir.EndDirectoryIndex, len(want))
+	}
+	if arr[1].Name != "track 10" {
+		t.Errorf("Sort changed the order of the array it was given")
+	}
+}
@@ -0,0 +1,37 @@
+package musicarray
+
+import (
+	"time"
+)
+
+// Summary answers how many songs a directory holds and how long they play for without walking the directory each time
+// it keeps running totals over the array, so a directory's totals are the difference between the totals at either end of its range
+type Summary struct {
+	songs     []int
+	durations []time.Duration
+}
+
+func (arr MusicArray) Summarize() Summary {
+	s := Summary{
+		songs:     make([]int, len(arr)+1),
+		durations: make([]time.Duration, len(arr)+1),
+	}
+	for i, e := range arr {
+		s.songs[i+1] = s.songs[i]
+		s.durations[i+1] = s.durations[i]
+		if e.Type == SongEntry {
+			s.songs[i+1]++
+			s.durations[i+1] += e.Song.Duration
+		}
+	}
+	return s
+}
+
+// Directory returns the number of songs within the directory at index, including those in subdirectories, and their total duration
+func (s Summary) Directory(arr MusicArray, index int) (int, time.Duration) {
+	end := arr[index].Dir.EndDirectoryIndex
+	if end > len(arr) || end < index+1 || len(s.songs) != len(arr)+1 {
+		return 0, 0
+	}
+	return s.songs[end] - s.songs[index+1], s.durations[end] - s.durations[index+1]
+}
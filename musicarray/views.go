@@ -0,0 +1,108 @@
+package musicarray
+
+import (
+	"sort"
+	"strings"
+)
+
+// FieldGetter looks up a named field, such as a tag, of an entry
+type FieldGetter func(e Entry, field string) (string, bool)
+
+// GroupBy builds a virtual tree of the songs in arr, nested by the value of each field in turn
+// eg fields of artist and album give Artist -> Album -> Track
+// songs missing a field are grouped under "Unknown <field>"; songs keep their paths so they still play the real files
+func (arr MusicArray) GroupBy(rootName, rootPath string, fields []string, get FieldGetter) MusicArray {
+	songs := MusicArray{}
+	for _, e := range arr {
+		if e.Type == SongEntry {
+			songs = append(songs, e)
+		}
+	}
+
+	result := MusicArray{Entry{
+		Type:  DirectoryEntry,
+		Name:  rootName,
+		Path:  rootPath,
+		Depth: 0,
+		Dir: Directory{
+			PrevDirectoryIndex: -1,
+			Virtual:            true,
+		},
+	}}
+	children, count := group(songs, rootPath, fields, 1, get)
+	result[0].Dir.ItemCount = count
+	return rebuildDirectoryIndices(append(result, children...))
+}
+
+// group returns the entries for songs grouped by the first of fields at the given depth and the number of items directly at that depth
+// the songs of the innermost groups are in track order, like an album read from disk with the track sort
+func group(songs MusicArray, path string, fields []string, depth int, get FieldGetter) (MusicArray, int) {
+	if len(fields) == 0 {
+		result := make(MusicArray, len(songs))
+		keys := make([]sortKey, len(songs))
+		for i, e := range songs {
+			e.Depth = depth
+			result[i] = e
+			keys[i] = result[i : i+1].sortKey()
+		}
+		sort.Stable(byTrack{result, keys})
+		return result, len(result)
+	}
+
+	groups := make(map[string]MusicArray)
+	names := []string{}
+	for _, e := range songs {
+		value, ok := get(e, fields[0])
+		if !ok || strings.TrimSpace(value) == "" {
+			value = "Unknown " + fields[0]
+		}
+		if _, exists := groups[value]; !exists {
+			names = append(names, value)
+		}
+		groups[value] = append(groups[value], e)
+	}
+	sort.SliceStable(names, func(a, b int) bool {
+		return strings.ToLower(names[a]) < strings.ToLower(names[b])
+	})
+
+	result := MusicArray{}
+	for _, name := range names {
+		groupPath := path + "/" + name
+		children, count := group(groups[name], groupPath, fields[1:], depth+1, get)
+		result = append(result, Entry{
+			Type:  DirectoryEntry,
+			Name:  name,
+			Path:  groupPath,
+			Depth: depth,
+			Dir: Directory{
+				PrevDirectoryIndex: -1,
+				ItemCount:          count,
+				Virtual:            true,
+			},
+		})
+		result = append(result, children...)
+	}
+	return result, len(names)
+}
+
+// byTrack orders songs by disc and track number, then by name
+type byTrack struct {
+	songs MusicArray
+	keys  []sortKey
+}
+
+func (b byTrack) Len() int {
+	return len(b.songs)
+}
+
+func (b byTrack) Swap(i, j int) {
+	b.songs[i], b.songs[j] = b.songs[j], b.songs[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+func (b byTrack) Less(i, j int) bool {
+	if c := compareKeys(b.keys[i], b.keys[j], SortTrack); c != 0 {
+		return c < 0
+	}
+	return NaturalLess(b.keys[i].name, b.keys[j].name)
+}
@@ -0,0 +1,63 @@
+package musicarray
+
+import (
+	"testing"
+)
+
+func song(name string, tags map[string]string) Entry {
+	return Entry{
+		Type: SongEntry,
+		Name: name,
+		Path: "/music/" + name,
+		Song: Song{Tags: tags},
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	arr := MusicArray{
+		{Type: DirectoryEntry, Name: "music", Path: "/music", ParentIndex: -1},
+		song("untagged 10", map[string]string{"album": "B"}),
+		song("untagged 9", map[string]string{"album": "B"}),
+		song("b3", map[string]string{"album": "B", "tracknumber": "3"}),
+		song("b1", map[string]string{"album": "B", "tracknumber": "1/3"}),
+		song("disc 2", map[string]string{"album": "B", "tracknumber": "1", "discnumber": "2"}),
+		song("a1", map[string]string{"album": "a", "tracknumber": "1"}),
+		song("none", map[string]string{}),
+	}
+	get := func(e Entry, field string) (string, bool) {
+		value, ok := e.Song.Tags[field]
+		return value, ok
+	}
+
+	grouped := arr.GroupBy("Albums", "/albums", []string{"album"}, get)
+	want := []struct {
+		name  string
+		depth int
+	}{
+		{"Albums", 0},
+		{"a", 1},
+		{"a1", 2},
+		{"B", 1},
+		{"b1", 2},
+		{"b3", 2},
+		{"disc 2", 2},
+		{"untagged 9", 2},
+		{"untagged 10", 2},
+		{"Unknown album", 1},
+		{"none", 2},
+	}
+	if len(grouped) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(grouped), len(want))
+	}
+	for n, w := range want {
+		if grouped[n].Name != w.name || grouped[n].Depth != w.depth {
+			t.Errorf("entry %d: got %s at depth %d, want %s at depth %d", n, grouped[n].Name, grouped[n].Depth, w.name, w.depth)
+		}
+	}
+	if grouped[0].Dir.ItemCount != 3 || grouped[3].Dir.ItemCount != 5 {
+		t.Errorf("got item counts %d and %d, want 3 and 5", grouped[0].Dir.ItemCount, grouped[3].Dir.ItemCount)
+	}
+	if grouped[4].ParentIndex != 3 {
+		t.Errorf("got parent index %d for b1, want 3", grouped[4].ParentIndex)
+	}
+}
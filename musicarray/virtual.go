@@ -0,0 +1,57 @@
+package musicarray
+
+// VirtualDirectory creates a directory that doesn't exist on disk containing the given entries
+// the entries are copied and shifted so that the shallowest of them sit directly inside the new directory
+func VirtualDirectory(name, path string, depth int, contents MusicArray) MusicArray {
+	arr := MusicArray{Entry{
+		Type:  DirectoryEntry,
+		Name:  name,
+		Path:  path,
+		Depth: depth,
+		Dir: Directory{
+			PrevDirectoryIndex: -1,
+			Virtual:            true,
+		},
+	}}
+
+	if len(contents) == 0 {
+		return arr
+	}
+	shallowest := contents[0].Depth
+	for _, e := range contents {
+		if e.Depth < shallowest {
+			shallowest = e.Depth
+		}
+	}
+	for _, e := range contents {
+		if e.Depth == shallowest {
+			arr[0].Dir.ItemCount++
+		}
+		e.Depth += depth + 1 - shallowest
+		arr = append(arr, e)
+	}
+	return arr
+}
+
+// AppendToRoot returns a new array with the entries placed inside the root directory after its existing contents
+// the entries should be at depth 1 or deeper; directory indices are rebuilt for the whole array
+func (arr MusicArray) AppendToRoot(entries MusicArray) MusicArray {
+	result := make(MusicArray, 0, len(arr)+len(entries))
+	result = append(result, arr...)
+	result = append(result, entries...)
+	for _, e := range entries {
+		if e.Depth == 1 {
+			result[0].Dir.ItemCount++
+		}
+	}
+	return rebuildDirectoryIndices(result)
+}
+
+func rebuildDirectoryIndices(arr MusicArray) MusicArray {
+	for i := range arr {
+		if arr[i].Type == DirectoryEntry {
+			arr[i].Dir.PrevDirectoryIndex = -1
+		}
+	}
+	return addDirectoryIndices(arr)
+}
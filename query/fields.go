@@ -0,0 +1,81 @@
+package query
+
+import (
+	"github.com/StructsNotClasses/mim/musicarray"
+
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FieldValue returns the value of a named field of an entry, the same way it is seen by queries
+func FieldValue(e musicarray.Entry, field string) (string, bool) {
+	return fieldValue(e, strings.ToLower(field), time.Now())
+}
+
+// fieldValue returns the value of a named field of an entry as a string and whether the entry has that field
+// fields that aren't built in are looked up in the song's tags
+func fieldValue(e musicarray.Entry, field string, now time.Time) (string, bool) {
+	switch field {
+	case "name":
+		return e.Name, true
+	case "path":
+		return e.Path, true
+	case "file":
+		return filepath.Base(e.Path), true
+	case "dir":
+		return filepath.Dir(e.Path), true
+	case "depth":
+		return fmt.Sprint(e.Depth), true
+	case "type":
+		if e.Type == musicarray.DirectoryEntry {
+			return "dir", true
+		}
+		return "song", true
+	}
+
+	if e.Type != musicarray.SongEntry {
+		return "", false
+	}
+
+	switch field {
+	case "ext":
+		return strings.TrimPrefix(strings.ToLower(filepath.Ext(e.Path)), "."), true
+	case "size":
+		return fmt.Sprint(e.Song.Size), true
+	case "added", "modified":
+		// age in days, so "added<=30" means modified within the last 30 days
+		return fmt.Sprint(int(now.Sub(e.Song.ModTime) / (24 * time.Hour))), true
+	case "duration":
+		return fmt.Sprint(int(e.Song.Duration / time.Second)), e.Song.Duration > 0
+	case "bitrate":
+		return fmt.Sprint(e.Song.Bitrate), e.Song.Bitrate > 0
+	case "samplerate":
+		return fmt.Sprint(e.Song.SampleRate), e.Song.SampleRate > 0
+	case "plays":
+		if e.Song.Stats == nil {
+			return "0", true
+		}
+		return fmt.Sprint(e.Song.Stats.PlayCount), true
+	case "year":
+		date, ok := e.Song.Tags["date"]
+		if !ok || len(date) < 4 {
+			return "", false
+		}
+		return date[:4], true
+	case "albumartist":
+		// most players treat a missing album artist as the artist
+		if value, ok := e.Song.Tags["albumartist"]; ok {
+			return value, true
+		}
+		field = "artist"
+	case "track":
+		field = "tracknumber"
+	case "disc":
+		field = "discnumber"
+	}
+
+	value, ok := e.Song.Tags[field]
+	return value, ok
+}
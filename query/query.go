@@ -0,0 +1,220 @@
+package query
+
+import (
+	"github.com/StructsNotClasses/mim/musicarray"
+
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query is a parsed library query
+// it is a list of alternatives separated by "or", each of which is a list of terms that all have to match
+// eg artist:"Boards of Canada" year>=1998 ext:flac -path:live
+type Query struct {
+	alternatives [][]term
+}
+
+type term struct {
+	negated bool
+	field   string // empty for bare words, which match the name or path
+	op      string
+	value   string
+}
+
+// operators in the order they are checked, so that two character operators are found before their one character prefixes
+var operators = []string{">=", "<=", "!=", ":", "=", ">", "<"}
+
+// Parse parses a query string
+// a term is either a bare word or <field><operator><value>, optionally prefixed by '-' to negate it
+// values containing spaces can be surrounded by quotation marks
+func Parse(s string) (Query, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return Query{}, err
+	}
+
+	if len(tokens) == 0 {
+		return Query{}, errors.New("query: the query is empty.")
+	}
+
+	q := Query{}
+	current := []term{}
+	for _, token := range tokens {
+		if strings.EqualFold(token, "or") || token == "|" {
+			if len(current) == 0 {
+				return Query{}, errors.New("query: 'or' must be placed between terms")
+			}
+			q.alternatives = append(q.alternatives, current)
+			current = []term{}
+			continue
+		}
+		t, err := parseTerm(token)
+		if err != nil {
+			return Query{}, err
+		}
+		current = append(current, t)
+	}
+	if len(current) == 0 && len(q.alternatives) != 0 {
+		return Query{}, errors.New("query: 'or' must be placed between terms")
+	}
+	q.alternatives = append(q.alternatives, current)
+	return q, nil
+}
+
+// tokenize splits the query at whitespace that isn't inside quotation marks, keeping the quotation marks
+func tokenize(s string) ([]string, error) {
+	tokens := []string{}
+	insideString := false
+	token := strings.Builder{}
+	for _, r := range s {
+		if r == '"' {
+			insideString = !insideString
+			token.WriteRune(r)
+		} else if (r == ' ' || r == '\t') && !insideString {
+			if token.Len() > 0 {
+				tokens = append(tokens, token.String())
+				token.Reset()
+			}
+		} else {
+			token.WriteRune(r)
+		}
+	}
+	if insideString {
+		return tokens, errors.New("query: unterminated string detected.")
+	}
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+	return tokens, nil
+}
+
+func parseTerm(token string) (term, error) {
+	t := term{}
+	if strings.HasPrefix(token, "-") && len(token) > 1 {
+		t.negated = true
+		token = token[1:]
+	}
+
+	// the field name is the identifier at the start of the token
+	end := 0
+	for end < len(token) && isFieldCharacter(token[end]) {
+		end++
+	}
+	if end > 0 {
+		for _, op := range operators {
+			if strings.HasPrefix(token[end:], op) {
+				t.field = strings.ToLower(token[:end])
+				t.op = op
+				t.value = unquote(token[end+len(op):])
+				if t.op != ":" && t.op != "=" && t.op != "!=" && t.value == "" {
+					return t, errors.New(fmt.Sprintf("query: '%s' is missing a value to compare against.", token))
+				}
+				return t, nil
+			}
+		}
+	}
+
+	t.op = ":"
+	t.value = unquote(token)
+	return t, nil
+}
+
+func isFieldCharacter(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '_'
+}
+
+func unquote(s string) string {
+	return strings.ReplaceAll(s, "\"", "")
+}
+
+// Match reports whether an entry satisfies the query
+// now is the time relative dates like added<=30 are counted back from, which is passed in rather than kept so saved queries don't go stale
+func (q Query) Match(e musicarray.Entry, now time.Time) bool {
+	for _, alternative := range q.alternatives {
+		matched := true
+		for _, t := range alternative {
+			if t.matches(e, now) == t.negated {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the indices of all entries in arr that satisfy the query at the time now, in order
+func (q Query) Filter(arr musicarray.MusicArray, now time.Time) []int {
+	matches := []int{}
+	for i, e := range arr {
+		if q.Match(e, now) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+func (t term) matches(e musicarray.Entry, now time.Time) bool {
+	if t.field == "" {
+		return containsFold(e.Name, t.value) || containsFold(e.Path, t.value)
+	}
+
+	actual, ok := fieldValue(e, t.field, now)
+	if !ok {
+		return false
+	}
+
+	switch t.op {
+	case ":":
+		return containsFold(actual, t.value)
+	case "=":
+		return compare(actual, t.value) == 0
+	case "!=":
+		return compare(actual, t.value) != 0
+	case ">=":
+		return compare(actual, t.value) >= 0
+	case "<=":
+		return compare(actual, t.value) <= 0
+	case ">":
+		return compare(actual, t.value) > 0
+	case "<":
+		return compare(actual, t.value) < 0
+	}
+	return false
+}
+
+// compare compares numerically if the expected value is a number, using the leading number of the actual value (so "5/12" compares as 5)
+// otherwise the values are compared as case insensitive strings
+func compare(actual, expected string) int {
+	if expectedNumber, err := strconv.ParseFloat(expected, 64); err == nil {
+		if actualNumber, ok := leadingNumber(actual); ok {
+			switch {
+			case actualNumber < expectedNumber:
+				return -1
+			case actualNumber > expectedNumber:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+	return strings.Compare(strings.ToLower(actual), strings.ToLower(expected))
+}
+
+func leadingNumber(s string) (float64, bool) {
+	end := 0
+	for end < len(s) && (s[end] >= '0' && s[end] <= '9' || s[end] == '.' || end == 0 && s[end] == '-') {
+		end++
+	}
+	n, err := strconv.ParseFloat(s[:end], 64)
+	return n, err == nil
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,151 @@
+package query
+
+import (
+	"github.com/StructsNotClasses/mim/musicarray"
+
+	"testing"
+	"time"
+)
+
+var now = time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func testEntries() map[string]musicarray.Entry {
+	return map[string]musicarray.Entry{
+		"roygbiv": {
+			Type: musicarray.SongEntry,
+			Name: "Roygbiv",
+			Path: "/music/boc/06 Roygbiv.flac",
+			Song: musicarray.Song{
+				Tags: map[string]string{
+					"artist":      "Boards of Canada",
+					"album":       "Music Has the Right to Children",
+					"date":        "1998-04-20",
+					"tracknumber": "6/17",
+					"genre":       "Ambient",
+				},
+				Duration: 151 * time.Second,
+				ModTime:  now.Add(-10 * 24 * time.Hour),
+			},
+		},
+		"live": {
+			Type: musicarray.SongEntry,
+			Name: "Dayvan Cowboy",
+			Path: "/music/boc/live/Dayvan Cowboy.mp3",
+			Song: musicarray.Song{
+				Tags: map[string]string{
+					"artist": "Boards of Canada",
+					"date":   "2006",
+				},
+				ModTime: now.Add(-400 * 24 * time.Hour),
+				Stats:   &musicarray.Stats{PlayCount: 3},
+			},
+		},
+		"untagged": {
+			Type: musicarray.SongEntry,
+			Name: "Track 10",
+			Path: "/music/other/track 10.mp3",
+			Song: musicarray.Song{ModTime: now},
+		},
+		"dir": {
+			Type: musicarray.DirectoryEntry,
+			Name: "boc",
+			Path: "/music/boc",
+		},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		// the entries the query should match, from testEntries
+		matches []string
+	}{
+		{"roygbiv", []string{"roygbiv"}},
+		{"boc", []string{"roygbiv", "live", "dir"}},
+		{`artist:"boards of canada"`, []string{"roygbiv", "live"}},
+		{`artist:"Boards of Canada" ext:flac`, []string{"roygbiv"}},
+		{`artist:"Boards of Canada" -path:live`, []string{"roygbiv"}},
+		{"-type:song", []string{"dir"}},
+		{"artist=boards", []string{}},
+		{`artist="boards of canada"`, []string{"roygbiv", "live"}},
+		{`artist!="boards of canada"`, []string{}},
+		{"year>=1998", []string{"roygbiv", "live"}},
+		{"year>1998", []string{"live"}},
+		{"year<2000", []string{"roygbiv"}},
+		{"year<=1998", []string{"roygbiv"}},
+		// track numbers written as 6/17 compare by their leading number
+		{"track=6", []string{"roygbiv"}},
+		{"duration>120", []string{"roygbiv"}},
+		{"plays>=1", []string{"live"}},
+		{"plays=0", []string{"roygbiv", "untagged"}},
+		{"ext:flac or ext:mp3", []string{"roygbiv", "live", "untagged"}},
+		{"genre:ambient | path:other", []string{"roygbiv", "untagged"}},
+		{"ext:mp3 year>2000 OR roygbiv", []string{"roygbiv", "live"}},
+		{"added<=30", []string{"roygbiv", "untagged"}},
+		{"added>365", []string{"live"}},
+		{"ARTIST:canada", []string{"roygbiv", "live"}},
+	}
+
+	entries := testEntries()
+	for _, test := range tests {
+		q, err := Parse(test.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.query, err)
+			continue
+		}
+		want := make(map[string]bool)
+		for _, name := range test.matches {
+			want[name] = true
+		}
+		for name, e := range entries {
+			if got := q.Match(e, now); got != want[name] {
+				t.Errorf("%q matching %s: got %v, want %v", test.query, name, got, want[name])
+			}
+		}
+	}
+}
+
+func TestMatchCountsAgeFromNow(t *testing.T) {
+	q, err := Parse("added<=30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := testEntries()["roygbiv"]
+	if !q.Match(e, now) {
+		t.Errorf("expected a song added 10 days ago to match")
+	}
+	if q.Match(e, now.Add(60*24*time.Hour)) {
+		t.Errorf("expected the same song not to match 60 days later")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"   ",
+		"or",
+		"artist:a or",
+		"or artist:a",
+		"artist:a or or year>1",
+		`artist:"unterminated`,
+		"year>=",
+		"year<",
+	} {
+		if _, err := Parse(query); err == nil {
+			t.Errorf("Parse(%q) succeeded, expected an error", query)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	entries := testEntries()
+	arr := musicarray.MusicArray{entries["dir"], entries["roygbiv"], entries["live"], entries["untagged"]}
+	q, err := Parse("artist:canada")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := q.Filter(arr, now)
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("got %v, want [1 2]", got)
+	}
+}
@@ -1,6 +1,7 @@
 package remote
 
 import (
+	"errors"
 	"io"
 	"log"
 )
@@ -22,3 +23,12 @@ func (r *Remote) Send(bs []byte) {
 		log.Fatal(err)
 	}
 }
+
+// TrySendString sends s without giving up on errors, for commands that may reach mplayer just after it exits
+func (r *Remote) TrySendString(s string) error {
+	if r == nil || r.Pipe == nil {
+		return errors.New("TrySendString: the remote has no pipe")
+	}
+	_, err := r.Pipe.Write([]byte(s))
+	return err
+}
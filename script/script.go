@@ -31,6 +31,11 @@ func (s Script) Run() error {
     return s.bytecode.Run()
 }
 
+// script.SetVariable changes the value of a global variable the script was compiled with before it is next run
+func (s Script) SetVariable(name string, value interface{}) error {
+    return s.bytecode.Set(name, value)
+}
+
 func (s Script) IsEmpty() bool {
     return len(s.contents) == 0
 }
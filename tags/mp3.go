@@ -0,0 +1,250 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+var id3Fields = map[string]string{
+	"TIT2": "title",
+	"TT2":  "title",
+	"TPE1": "artist",
+	"TP1":  "artist",
+	"TPE2": "albumartist",
+	"TP2":  "albumartist",
+	"TALB": "album",
+	"TAL":  "album",
+	"TCON": "genre",
+	"TCO":  "genre",
+	"TRCK": "tracknumber",
+	"TRK":  "tracknumber",
+	"TPOS": "discnumber",
+	"TPA":  "discnumber",
+	"TYER": "date",
+	"TYE":  "date",
+	"TDRC": "date",
+	"TCOM": "composer",
+	"TCM":  "composer",
+}
+
+// readMP3 reads an id3v2 tag if present and then the first mpeg frame header for the stream properties
+func readMP3(f *os.File, info *Info) error {
+	audioStart, err := readID3v2(f, info)
+	if err != nil {
+		return err
+	}
+	return readMPEGFrame(f, audioStart, info)
+}
+
+// readID3v2 parses the id3v2 tag at the start of the file and returns the offset of the first byte after it
+func readID3v2(f *os.File, info *Info) (int64, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return 0, err
+	}
+	if string(header[:3]) != "ID3" {
+		return 0, nil
+	}
+	major := header[3]
+	flags := header[5]
+	size := syncsafe(header[6:10])
+	end := int64(10 + size)
+	if flags&0x10 != 0 {
+		// footer present
+		end += 10
+	}
+
+	if !fits(f, 10, int64(size)) {
+		return end, errMalformed
+	}
+	tag := make([]byte, size)
+	if _, err := io.ReadFull(f, tag); err != nil {
+		return end, err
+	}
+
+	if flags&0x40 != 0 && len(tag) >= 4 {
+		// skip the extended header
+		if major == 4 {
+			tag = tag[min(syncsafe(tag[:4]), len(tag)):]
+		} else {
+			tag = tag[min(4+int(binary.BigEndian.Uint32(tag[:4])), len(tag)):]
+		}
+	}
+
+	idLength, headerLength := 4, 10
+	if major == 2 {
+		idLength, headerLength = 3, 6
+	}
+	for len(tag) >= headerLength && tag[0] != 0 {
+		id := string(tag[:idLength])
+		var frameSize int
+		switch major {
+		case 2:
+			frameSize = int(tag[3])<<16 | int(tag[4])<<8 | int(tag[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(tag[4:8]))
+		default:
+			frameSize = syncsafe(tag[4:8])
+		}
+		if frameSize < 0 || headerLength+frameSize > len(tag) {
+			break
+		}
+		readID3Frame(id, tag[headerLength:headerLength+frameSize], info)
+		tag = tag[headerLength+frameSize:]
+	}
+	return end, nil
+}
+
+func readID3Frame(id string, body []byte, info *Info) {
+	if len(body) == 0 {
+		return
+	}
+	switch {
+	case id == "TXXX" || id == "TXX":
+		// user defined text: encoding, description, value
+		parts := splitID3Strings(body[0], body[1:])
+		if len(parts) >= 2 {
+			info.set(parts[0], parts[1])
+		}
+	case id == "POPM" || id == "POP":
+		// popularimeter: email, one byte rating out of 255, play counter
+		if email := bytes.IndexByte(body, 0); email >= 0 && email+1 < len(body) {
+			rating := int(body[email+1])
+			if rating > 0 {
+				info.set("rating", fmt.Sprint((rating*5+254)/255))
+			}
+		}
+	default:
+		if field, ok := id3Fields[id]; ok {
+			parts := splitID3Strings(body[0], body[1:])
+			if len(parts) > 0 {
+				info.set(field, parts[0])
+			}
+		}
+	}
+}
+
+// splitID3Strings decodes null separated strings in the given id3 text encoding
+func splitID3Strings(encoding byte, data []byte) []string {
+	var s string
+	switch encoding {
+	case 1, 2:
+		s = decodeUTF16(data, encoding == 2)
+	case 3:
+		s = string(data)
+	default:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		s = string(runes)
+	}
+	return strings.Split(strings.TrimRight(s, "\x00"), "\x00")
+}
+
+// decodeUTF16 decodes utf-16 text, honoring byte order marks at the start of each string
+func decodeUTF16(data []byte, bigEndian bool) string {
+	var units []uint16
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0xFF && data[i+1] == 0xFE {
+			bigEndian = false
+			continue
+		} else if data[i] == 0xFE && data[i+1] == 0xFF {
+			bigEndian = true
+			continue
+		}
+		if bigEndian {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		} else {
+			units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+func syncsafe(bs []byte) int {
+	return int(bs[0]&0x7F)<<21 | int(bs[1]&0x7F)<<14 | int(bs[2]&0x7F)<<7 | int(bs[3]&0x7F)
+}
+
+var mpeg1Layer3Bitrates = []int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320}
+var mpeg2Layer3Bitrates = []int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160}
+var mpeg1SampleRates = []int{44100, 48000, 32000}
+
+// readMPEGFrame finds the first layer III frame header after audioStart
+// the duration is taken from a xing/info header if the file has one and estimated from the bitrate otherwise
+func readMPEGFrame(f *os.File, audioStart int64, info *Info) error {
+	buf := make([]byte, 8192)
+	n, err := f.ReadAt(buf, audioStart)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	buf = buf[:n]
+
+	for i := 0; i+4 <= len(buf); i++ {
+		if buf[i] != 0xFF || buf[i+1]&0xE0 != 0xE0 {
+			continue
+		}
+		version := (buf[i+1] >> 3) & 3
+		layer := (buf[i+1] >> 1) & 3
+		bitrateIndex := int(buf[i+2] >> 4)
+		rateIndex := int(buf[i+2]>>2) & 3
+		if version == 1 || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+			continue
+		}
+		mono := buf[i+3]>>6 == 3
+
+		samplesPerFrame := 1152
+		sideInfo := 32
+		info.SampleRate = mpeg1SampleRates[rateIndex]
+		info.Bitrate = mpeg1Layer3Bitrates[bitrateIndex]
+		if mono {
+			sideInfo = 17
+		}
+		if version != 3 {
+			samplesPerFrame = 576
+			info.SampleRate /= 2
+			if version == 0 {
+				info.SampleRate /= 2
+			}
+			info.Bitrate = mpeg2Layer3Bitrates[bitrateIndex]
+			sideInfo = 17
+			if mono {
+				sideInfo = 9
+			}
+		}
+
+		stat, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		audioSize := stat.Size() - audioStart - int64(i)
+
+		xing := i + 4 + sideInfo
+		if xing+12 <= len(buf) && (string(buf[xing:xing+4]) == "Xing" || string(buf[xing:xing+4]) == "Info") {
+			if binary.BigEndian.Uint32(buf[xing+4:xing+8])&1 != 0 {
+				frames := int64(binary.BigEndian.Uint32(buf[xing+8 : xing+12]))
+				info.Duration = time.Duration(frames*int64(samplesPerFrame)) * time.Second / time.Duration(info.SampleRate)
+				if info.Duration >= time.Millisecond {
+					info.Bitrate = int(audioSize * 8 / int64(info.Duration/time.Millisecond))
+				}
+				return nil
+			}
+		}
+		info.Duration = time.Duration(audioSize*8/int64(info.Bitrate)) * time.Millisecond
+		return nil
+	}
+	return nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
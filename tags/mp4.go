@@ -0,0 +1,187 @@
+package tags
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+var mp4Fields = map[string]string{
+	"\xa9nam": "title",
+	"\xa9ART": "artist",
+	"aART":    "albumartist",
+	"\xa9alb": "album",
+	"\xa9day": "date",
+	"\xa9gen": "genre",
+	"\xa9wrt": "composer",
+}
+
+type mp4Atom struct {
+	kind string
+	body []byte
+}
+
+// readMP4 locates the top level moov atom and reads the duration and the itunes style ilst metadata from it
+func readMP4(f *os.File, info *Info) error {
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	for offset := int64(0); offset+8 <= stat.Size(); {
+		header := make([]byte, 16)
+		if _, err := f.ReadAt(header[:8], offset); err != nil {
+			return err
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		kind := string(header[4:8])
+		headerLength := int64(8)
+		if size == 1 {
+			if _, err := f.ReadAt(header[8:], offset+8); err != nil {
+				return err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:]))
+			headerLength = 16
+		} else if size == 0 {
+			size = stat.Size() - offset
+		}
+		if size < headerLength || size > stat.Size()-offset {
+			return errMalformed
+		}
+
+		if kind == "moov" {
+			moov := make([]byte, size-headerLength)
+			if _, err := f.ReadAt(moov, offset+headerLength); err != nil && err != io.EOF {
+				return err
+			}
+			readMoov(moov, info)
+			if info.Duration >= time.Millisecond {
+				info.Bitrate = int(stat.Size() * 8 / int64(info.Duration/time.Millisecond))
+			}
+			return nil
+		}
+		offset += size
+	}
+	return errMalformed
+}
+
+// mp4Children splits the body of a container atom into its child atoms
+func mp4Children(body []byte) []mp4Atom {
+	var atoms []mp4Atom
+	for len(body) >= 8 {
+		size := int(binary.BigEndian.Uint32(body[:4]))
+		if size < 8 || size > len(body) {
+			break
+		}
+		atoms = append(atoms, mp4Atom{kind: string(body[4:8]), body: body[8:size]})
+		body = body[size:]
+	}
+	return atoms
+}
+
+func readMoov(moov []byte, info *Info) {
+	for _, atom := range mp4Children(moov) {
+		switch atom.kind {
+		case "mvhd":
+			readMvhd(atom.body, info)
+		case "trak":
+			if info.SampleRate == 0 {
+				info.SampleRate = trakTimescale(atom.body)
+			}
+		case "udta":
+			for _, child := range mp4Children(atom.body) {
+				// meta is a full atom, so it has 4 bytes of version and flags before its children
+				if child.kind == "meta" && len(child.body) >= 4 {
+					for _, list := range mp4Children(child.body[4:]) {
+						if list.kind == "ilst" {
+							readIlst(list.body, info)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func readMvhd(body []byte, info *Info) {
+	if len(body) < 20 {
+		return
+	}
+	var timescale, duration int64
+	if body[0] == 1 {
+		if len(body) < 32 {
+			return
+		}
+		timescale = int64(binary.BigEndian.Uint32(body[20:24]))
+		duration = int64(binary.BigEndian.Uint64(body[24:32]))
+	} else {
+		timescale = int64(binary.BigEndian.Uint32(body[12:16]))
+		duration = int64(binary.BigEndian.Uint32(body[16:20]))
+	}
+	if timescale > 0 {
+		info.Duration = time.Duration(duration) * time.Second / time.Duration(timescale)
+	}
+}
+
+// trakTimescale returns the media timescale of a track, which for audio tracks is the sample rate
+func trakTimescale(trak []byte) int {
+	for _, atom := range mp4Children(trak) {
+		if atom.kind != "mdia" {
+			continue
+		}
+		for _, child := range mp4Children(atom.body) {
+			if child.kind != "mdhd" || len(child.body) < 24 {
+				continue
+			}
+			if child.body[0] == 1 {
+				return int(binary.BigEndian.Uint32(child.body[20:24]))
+			}
+			return int(binary.BigEndian.Uint32(child.body[12:16]))
+		}
+	}
+	return 0
+}
+
+func readIlst(ilst []byte, info *Info) {
+	for _, item := range mp4Children(ilst) {
+		var name string
+		var value []byte
+		for _, child := range mp4Children(item.body) {
+			switch child.kind {
+			case "name":
+				if len(child.body) >= 4 {
+					name = string(child.body[4:])
+				}
+			case "data":
+				// data atoms start with 4 bytes of type and 4 bytes of locale
+				if len(child.body) >= 8 {
+					value = child.body[8:]
+				}
+			}
+		}
+
+		switch item.kind {
+		case "trkn", "disk":
+			if len(value) >= 4 {
+				number := int(binary.BigEndian.Uint16(value[2:4]))
+				field := "tracknumber"
+				if item.kind == "disk" {
+					field = "discnumber"
+				}
+				if number > 0 {
+					info.set(field, fmt.Sprint(number))
+				}
+			}
+		case "----":
+			if name != "" {
+				info.set(name, string(value))
+			}
+		default:
+			if field, ok := mp4Fields[item.kind]; ok {
+				info.set(field, string(value))
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"time"
+)
+
+// oggPacketReader reassembles packets from the pages of an ogg stream
+type oggPacketReader struct {
+	r       io.Reader
+	lacing  []byte
+	pending []byte
+}
+
+func (o *oggPacketReader) readPage() error {
+	header := make([]byte, 27)
+	if _, err := io.ReadFull(o.r, header); err != nil {
+		return err
+	}
+	if string(header[:4]) != "OggS" {
+		return errMalformed
+	}
+	o.lacing = make([]byte, header[26])
+	_, err := io.ReadFull(o.r, o.lacing)
+	return err
+}
+
+func (o *oggPacketReader) nextPacket() ([]byte, error) {
+	packet := o.pending
+	o.pending = nil
+	for {
+		if len(o.lacing) == 0 {
+			if err := o.readPage(); err != nil {
+				return nil, err
+			}
+		}
+		for len(o.lacing) > 0 {
+			size := o.lacing[0]
+			o.lacing = o.lacing[1:]
+			segment := make([]byte, size)
+			if _, err := io.ReadFull(o.r, segment); err != nil {
+				return nil, err
+			}
+			packet = append(packet, segment...)
+			// a segment shorter than 255 bytes terminates the packet
+			if size < 255 {
+				return packet, nil
+			}
+		}
+	}
+}
+
+// readOgg reads the identification and comment headers of an ogg vorbis or opus stream and the last granule position for the duration
+func readOgg(f *os.File, info *Info) error {
+	packets := oggPacketReader{r: f}
+	id, err := packets.nextPacket()
+	if err != nil {
+		return err
+	}
+
+	var commentPrefix string
+	granuleRate := 0
+	switch {
+	case bytes.HasPrefix(id, []byte("\x01vorbis")) && len(id) >= 24:
+		commentPrefix = "\x03vorbis"
+		info.SampleRate = int(binary.LittleEndian.Uint32(id[12:16]))
+		info.Bitrate = int(int32(binary.LittleEndian.Uint32(id[20:24]))) / 1000
+		granuleRate = info.SampleRate
+	case bytes.HasPrefix(id, []byte("OpusHead")) && len(id) >= 16:
+		commentPrefix = "OpusTags"
+		info.SampleRate = int(binary.LittleEndian.Uint32(id[12:16]))
+		// opus granule positions are always counted at 48kHz
+		granuleRate = 48000
+	default:
+		return ErrUnsupported
+	}
+
+	comment, err := packets.nextPacket()
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(comment, []byte(commentPrefix)) {
+		return errMalformed
+	}
+	if err := readVorbisComment(comment[len(commentPrefix):], info); err != nil {
+		return err
+	}
+
+	if granule, ok := lastGranule(f); ok && granuleRate > 0 {
+		info.Duration = time.Duration(granule) * time.Second / time.Duration(granuleRate)
+		if info.Bitrate <= 0 && info.Duration >= time.Millisecond {
+			if stat, err := f.Stat(); err == nil {
+				info.Bitrate = int(stat.Size() * 8 / int64(info.Duration/time.Millisecond))
+			}
+		}
+	}
+	return nil
+}
+
+// lastGranule finds the granule position of the final page, which is the length of the stream in samples
+func lastGranule(f *os.File) (int64, bool) {
+	const tailSize = 65536
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, false
+	}
+	offset := stat.Size() - tailSize
+	if offset < 0 {
+		offset = 0
+	}
+	tail := make([]byte, stat.Size()-offset)
+	if _, err := f.ReadAt(tail, offset); err != nil && err != io.EOF {
+		return 0, false
+	}
+	last := bytes.LastIndex(tail, []byte("OggS"))
+	if last < 0 || last+14 > len(tail) {
+		return 0, false
+	}
+	return int64(binary.LittleEndian.Uint64(tail[last+6 : last+14])), true
+}
@@ -0,0 +1,65 @@
+package tags
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Info holds the metadata that could be read from an audio file
+// field names are lowercase vorbis-comment style names such as "title", "artist", "album", "date" and "tracknumber"
+type Info struct {
+	Fields     map[string]string
+	Duration   time.Duration
+	SampleRate int
+	Bitrate    int // kbps
+}
+
+var ErrUnsupported = errors.New("tags: unsupported file format")
+
+// Read reads whatever metadata it can from the file at path
+// formats are recognized by extension; an Info with an empty Fields map is returned alongside any error
+func Read(path string) (Info, error) {
+	info := Info{Fields: make(map[string]string)}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return info, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".flac":
+		err = readFlac(f, &info)
+	case ".mp3":
+		err = readMP3(f, &info)
+	case ".ogg", ".opus":
+		err = readOgg(f, &info)
+	case ".m4a", ".mp4":
+		err = readMP4(f, &info)
+	default:
+		err = ErrUnsupported
+	}
+	return info, err
+}
+
+// fits reports whether length bytes starting at offset are within the file
+// lengths read from headers are checked with it before anything is allocated for them, since a broken or malicious file could claim any size
+func fits(f *os.File, offset, length int64) bool {
+	stat, err := f.Stat()
+	return err == nil && length >= 0 && offset >= 0 && length <= stat.Size()-offset
+}
+
+// set stores a field value, ignoring empty values and keeping the first value seen for a field
+func (info *Info) set(field, value string) {
+	field = strings.ToLower(field)
+	value = strings.TrimRight(value, "\x00")
+	if value == "" {
+		return
+	}
+	if _, exists := info.Fields[field]; !exists {
+		info.Fields[field] = value
+	}
+}
@@ -0,0 +1,106 @@
+package tags
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func be32(n uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, n)
+	return b
+}
+
+func le32(n uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, n)
+	return b
+}
+
+func join(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// vorbisComment builds a vorbis comment block with an empty vendor string
+func vorbisComment(comments ...string) []byte {
+	b := join(le32(0), le32(uint32(len(comments))))
+	for _, c := range comments {
+		b = join(b, le32(uint32(len(c))), []byte(c))
+	}
+	return b
+}
+
+func flacBlock(last bool, blockType byte, length int, body []byte) []byte {
+	if last {
+		blockType |= 0x80
+	}
+	return join([]byte{blockType, byte(length >> 16), byte(length >> 8), byte(length)}, body)
+}
+
+func TestReadMalformed(t *testing.T) {
+	comment := vorbisComment("TITLE=x")
+	tests := []struct {
+		name     string
+		file     string
+		contents []byte
+	}{
+		{"empty mp3", "a.mp3", []byte{}},
+		{"truncated id3 header", "a.mp3", []byte("ID3\x03")},
+		// syncsafe 0x7f7f7f7f is about 256MB, far more than the file
+		{"oversized id3 tag", "a.mp3", []byte("ID3\x03\x00\x00\x7f\x7f\x7f\x7fTIT2")},
+		{"truncated flac magic", "a.flac", []byte("fLa")},
+		{"truncated flac block header", "a.flac", []byte("fLaC\x84\x00")},
+		{"oversized flac block", "a.flac", join([]byte("fLaC"), flacBlock(true, 4, 0xFFFFFF, comment))},
+		{"vorbis comment longer than its block", "a.flac", join([]byte("fLaC"), flacBlock(true, 4, 8, le32(0)), le32(1000))},
+		{"truncated mp4 atom", "a.m4a", []byte("\x00\x00\x00\x20moov")},
+		{"oversized mp4 atom", "a.m4a", join(be32(0x7FFFFFFF), []byte("moov"), make([]byte, 8))},
+		{"oversized 64 bit mp4 atom", "a.m4a", join(be32(1), []byte("moov"), []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xF0})},
+		{"mp4 atom smaller than its header", "a.m4a", join(be32(4), []byte("moov"))},
+		{"truncated ogg page", "a.ogg", []byte("OggS\x00")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.file)
+			if err := os.WriteFile(path, test.contents, 0644); err != nil {
+				t.Fatal(err)
+			}
+			info, err := Read(path)
+			if err == nil {
+				t.Errorf("expected an error, read %+v", info)
+			}
+			if info.Fields == nil {
+				t.Errorf("expected an empty Fields map alongside the error")
+			}
+		})
+	}
+}
+
+func TestReadFlacComment(t *testing.T) {
+	comment := vorbisComment("TITLE=Song", "ARTIST=Band", "ignored", "ARTIST=Second")
+	contents := join([]byte("fLaC"), flacBlock(true, 4, len(comment), comment))
+	path := filepath.Join(t.TempDir(), "a.flac")
+	if err := os.WriteFile(path, contents, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"title": "Song", "artist": "Band"}
+	if len(info.Fields) != len(want) {
+		t.Errorf("got fields %v, want %v", info.Fields, want)
+	}
+	for k, v := range want {
+		if info.Fields[k] != v {
+			t.Errorf("field %s: got %q, want %q", k, info.Fields[k], v)
+		}
+	}
+}
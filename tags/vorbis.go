@@ -0,0 +1,112 @@
+package tags
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+var errMalformed = errors.New("tags: malformed metadata")
+
+// readFlac reads the STREAMINFO and VORBIS_COMMENT metadata blocks at the start of a flac file
+func readFlac(f *os.File, info *Info) error {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return err
+	}
+	if string(magic) != "fLaC" {
+		return errMalformed
+	}
+
+	for last := false; !last; {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(f, header); err != nil {
+			return err
+		}
+		last = header[0]&0x80 != 0
+		blockType := header[0] & 0x7F
+		length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+
+		switch blockType {
+		case 0, 4:
+			offset, err := f.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			if !fits(f, offset, int64(length)) {
+				return errMalformed
+			}
+			block := make([]byte, length)
+			if _, err := io.ReadFull(f, block); err != nil {
+				return err
+			}
+			if blockType == 0 {
+				readStreamInfo(block, info)
+			} else if err := readVorbisComment(block, info); err != nil {
+				return err
+			}
+		default:
+			if _, err := f.Seek(int64(length), io.SeekCurrent); err != nil {
+				return err
+			}
+		}
+	}
+
+	if stat, err := f.Stat(); err == nil && info.Duration >= time.Millisecond {
+		info.Bitrate = int(stat.Size() * 8 / int64(info.Duration/time.Millisecond))
+	}
+	return nil
+}
+
+func readStreamInfo(block []byte, info *Info) {
+	if len(block) < 18 {
+		return
+	}
+	sampleRate := int(block[10])<<12 | int(block[11])<<4 | int(block[12])>>4
+	totalSamples := int64(block[13]&0x0F)<<32 | int64(binary.BigEndian.Uint32(block[14:18]))
+	info.SampleRate = sampleRate
+	if sampleRate > 0 {
+		info.Duration = time.Duration(totalSamples) * time.Second / time.Duration(sampleRate)
+	}
+}
+
+// readVorbisComment parses a vorbis comment block, which is shared by flac and ogg files
+// the block starts with the vendor string followed by a list of KEY=value strings, all prefixed by little-endian lengths
+func readVorbisComment(block []byte, info *Info) error {
+	next := func() (string, error) {
+		if len(block) < 4 {
+			return "", errMalformed
+		}
+		length := binary.LittleEndian.Uint32(block)
+		block = block[4:]
+		if uint32(len(block)) < length {
+			return "", errMalformed
+		}
+		s := string(block[:length])
+		block = block[length:]
+		return s, nil
+	}
+
+	// vendor string
+	if _, err := next(); err != nil {
+		return err
+	}
+	if len(block) < 4 {
+		return errMalformed
+	}
+	count := binary.LittleEndian.Uint32(block)
+	block = block[4:]
+	for i := uint32(0); i < count; i++ {
+		comment, err := next()
+		if err != nil {
+			return err
+		}
+		if eq := strings.IndexByte(comment, '='); eq > 0 {
+			info.set(comment[:eq], comment[eq+1:])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+// Package textwidth measures strings in terminal columns rather than bytes or runes
+// combining characters take no columns and East Asian wide characters and most emoji take two
+package textwidth
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ellipsis replaces the end of anything that was truncated
+const Ellipsis = "…"
+
+// Rune returns the number of columns r takes up
+func Rune(r rune) int {
+	switch {
+	case r < 0x20 || r >= 0x7f && r < 0xa0:
+		// control characters aren't printed as themselves anyway
+		return 0
+	case r < 0x300:
+		// nothing before the combining diacritics is combining or wide
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case r >= 0x1160 && r <= 0x11ff:
+		// hangul vowels and final consonants combine with the preceding consonant
+		return 0
+	case isWide(r):
+		return 2
+	}
+	return 1
+}
+
+// String returns the number of columns s takes up
+func String(s string) int {
+	width := 0
+	for _, r := range s {
+		width += Rune(r)
+	}
+	return width
+}
+
+// Truncate shortens s to fit in width columns, ending it with an ellipsis if anything was removed
+// a wide character is never split, so the result may be a column narrower than width
+func Truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if String(s) <= width {
+		return s
+	}
+
+	available := width - String(Ellipsis)
+	if available < 0 {
+		// too narrow for even the ellipsis
+		return ""
+	}
+	used := 0
+	end := 0
+	for i, r := range s {
+		w := Rune(r)
+		if used+w > available {
+			break
+		}
+		used += w
+		end = i + utf8.RuneLen(r)
+	}
+	return s[:end] + Ellipsis
+}
+
+// Pad truncates s to width columns and fills any remaining columns with spaces
+func Pad(s string, width int) string {
+	s = Truncate(s, width)
+	if fill := width - String(s); fill > 0 {
+		return s + strings.Repeat(" ", fill)
+	}
+	return s
+}
+
+// isWide reports whether r is in one of the East Asian wide or fullwidth ranges, which include most emoji
+func isWide(r rune) bool {
+	i := sort.Search(len(wideRanges), func(i int) bool {
+		return wideRanges[i][1] >= r
+	})
+	return i < len(wideRanges) && wideRanges[i][0] <= r
+}
+
+// wideRanges are inclusive and sorted so they can be searched
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f},
+	{0x231a, 0x231b},
+	{0x2329, 0x232a},
+	{0x23e9, 0x23ec},
+	{0x23f0, 0x23f0},
+	{0x23f3, 0x23f3},
+	{0x25fd, 0x25fe},
+	{0x2614, 0x2615},
+	{0x2648, 0x2653},
+	{0x267f, 0x267f},
+	{0x2693, 0x2693},
+	{0x26a1, 0x26a1},
+	{0x26aa, 0x26ab},
+	{0x26bd, 0x26be},
+	{0x26c4, 0x26c5},
+	{0x26ce, 0x26ce},
+	{0x26d4, 0x26d4},
+	{0x26ea, 0x26ea},
+	{0x26f2, 0x26f3},
+	{0x26f5, 0x26f5},
+	{0x26fa, 0x26fa},
+	{0x26fd, 0x26fd},
+	{0x2705, 0x2705},
+	{0x270a, 0x270b},
+	{0x2728, 0x2728},
+	{0x274c, 0x274c},
+	{0x274e, 0x274e},
+	{0x2753, 0x2755},
+	{0x2757, 0x2757},
+	{0x2795, 0x2797},
+	{0x27b0, 0x27b0},
+	{0x27bf, 0x27bf},
+	{0x2b1b, 0x2b1c},
+	{0x2b50, 0x2b50},
+	{0x2b55, 0x2b55},
+	{0x2e80, 0x303e},
+	{0x3041, 0x33ff},
+	{0x3400, 0x4dbf},
+	{0x4e00, 0x9fff},
+	{0xa000, 0xa4cf},
+	{0xa960, 0xa97f},
+	{0xac00, 0xd7a3},
+	{0xf900, 0xfaff},
+	{0xfe10, 0xfe19},
+	{0xfe30, 0xfe6f},
+	{0xff00, 0xff60},
+	{0xffe0, 0xffe6},
+	{0x16fe0, 0x16fe4},
+	{0x17000, 0x18aff},
+	{0x1b000, 0x1b2ff},
+	{0x1f004, 0x1f004},
+	{0x1f0cf, 0x1f0cf},
+	{0x1f18e, 0x1f18e},
+	{0x1f191, 0x1f19a},
+	{0x1f200, 0x1f202},
+	{0x1f210, 0x1f23b},
+	{0x1f240, 0x1f248},
+	{0x1f250, 0x1f251},
+	{0x1f260, 0x1f265},
+	{0x1f300, 0x1f320},
+	{0x1f32d, 0x1f335},
+	{0x1f337, 0x1f37c},
+	{0x1f37e, 0x1f393},
+	{0x1f3a0, 0x1f3ca},
+	{0x1f3cf, 0x1f3d3},
+	{0x1f3e0, 0x1f3f0},
+	{0x1f3f4, 0x1f3f4},
+	{0x1f3f8, 0x1f43e},
+	{0x1f440, 0x1f440},
+	{0x1f442, 0x1f4fc},
+	{0x1f4ff, 0x1f53d},
+	{0x1f54b, 0x1f54e},
+	{0x1f550, 0x1f567},
+	{0x1f57a, 0x1f57a},
+	{0x1f595, 0x1f596},
+	{0x1f5a4, 0x1f5a4},
+	{0x1f5fb, 0x1f64f},
+	{0x1f680, 0x1f6c5},
+	{0x1f6cc, 0x1f6cc},
+	{0x1f6d0, 0x1f6d2},
+	{0x1f6d5, 0x1f6d7},
+	{0x1f6eb, 0x1f6ec},
+	{0x1f6f4, 0x1f6fc},
+	{0x1f7e0, 0x1f7eb},
+	{0x1f90c, 0x1f93a},
+	{0x1f93c, 0x1f945},
+	{0x1f947, 0x1f9ff},
+	{0x1fa70, 0x1faff},
+	{0x20000, 0x2fffd},
+	{0x30000, 0x3fffd},
+}
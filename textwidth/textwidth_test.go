@@ -0,0 +1,104 @@
+package textwidth
+
+import (
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"héllo", 5},
+		// e followed by a combining acute accent
+		{"he\u0301llo", 5},
+		{"日本語", 6},
+		{"ｈｉ", 4},
+		{"한국어", 6},
+		// a hangul syllable written as its separate jamo
+		{"\u1100\u1161\u11a8", 2},
+		{"🎵 song", 7},
+		{"a\tb\x7f", 2},
+		{"a\u200bb", 2},
+		{Ellipsis, 1},
+	}
+
+	for _, test := range tests {
+		if got := String(test.s); got != test.width {
+			t.Errorf("String(%q) = %d, want %d", test.s, got, test.width)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"abcdef", 6, "abcdef"},
+		{"abcdef", 10, "abcdef"},
+		{"abcdef", 5, "abcd…"},
+		{"abcdef", 2, "a…"},
+		{"abcdef", 1, "…"},
+		{"abcdef", 0, ""},
+		{"abcdef", -3, ""},
+		{"", 0, ""},
+		{"", 5, ""},
+		{"日本語", 6, "日本語"},
+		// a wide character that would be split by the edge is left out, leaving the result a column short
+		{"日本語", 5, "日本…"},
+		{"日本語", 4, "日…"},
+		{"日本語", 2, "…"},
+		{"日本語", 1, "…"},
+		{"a日本", 4, "a日…"},
+		{"a日本", 3, "a…"},
+		{"🎵🎶🎷", 4, "🎵…"},
+		{"🎵🎶🎷", 3, "🎵…"},
+		// combining characters stay with the character they're on
+		{"ae\u0301bc", 3, "ae\u0301…"},
+		{"ae\u0301bc", 4, "ae\u0301bc"},
+	}
+
+	for _, test := range tests {
+		got := Truncate(test.s, test.width)
+		if got != test.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", test.s, test.width, got, test.want)
+		}
+		if test.width > 0 && String(got) > test.width {
+			t.Errorf("Truncate(%q, %d) = %q, which is %d columns", test.s, test.width, got, String(got))
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc  "},
+		{"abc", 3, "abc"},
+		{"abcdef", 4, "abc…"},
+		{"", 3, "   "},
+		{"abc", 0, ""},
+		{"abc", -1, ""},
+		{"日本", 5, "日本 "},
+		// the column left by a wide character that didn't fit is filled too
+		{"日本語", 4, "日… "},
+		{"日本語", 2, "… "},
+		{"🎵x", 4, "🎵x "},
+	}
+
+	for _, test := range tests {
+		got := Pad(test.s, test.width)
+		if got != test.want {
+			t.Errorf("Pad(%q, %d) = %q, want %q", test.s, test.width, got, test.want)
+		}
+		if test.width >= 0 && String(got) != test.width {
+			t.Errorf("Pad(%q, %d) = %q, which is %d columns", test.s, test.width, got, String(got))
+		}
+	}
+}
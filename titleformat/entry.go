@@ -0,0 +1,65 @@
+package titleformat
+
+import (
+	"github.com/StructsNotClasses/mim/musicarray"
+	"github.com/StructsNotClasses/mim/query"
+
+	"fmt"
+	"time"
+)
+
+// EntryLookup looks up fields of the entry at index the same way queries do, except that durations are shown as minutes and seconds
+// a directory has a tag if every song directly inside of it has the same value for it, so an album directory has the album's artist and year
+func EntryLookup(arr musicarray.MusicArray, index int) Lookup {
+	e := arr[index]
+	return func(field string) (string, bool) {
+		if field == "duration" {
+			return FormatDuration(e.Song.Duration), e.Type == musicarray.SongEntry && e.Song.Duration > 0
+		}
+		if value, ok := query.FieldValue(e, field); ok || e.Type == musicarray.SongEntry {
+			return value, ok
+		}
+		return sharedValue(arr, index, field)
+	}
+}
+
+// sharedValue returns the value of a field common to every song directly inside of the directory at index
+func sharedValue(arr musicarray.MusicArray, index int, field string) (string, bool) {
+	shared, found := "", false
+	for i := index + 1; i < arr[index].Dir.EndDirectoryIndex && i < len(arr); i++ {
+		e := arr[i]
+		if e.Type == musicarray.DirectoryEntry {
+			// skip over the contents of subdirectories
+			if e.Dir.EndDirectoryIndex > i {
+				i = e.Dir.EndDirectoryIndex - 1
+			}
+			continue
+		}
+		value, ok := query.FieldValue(e, field)
+		if !ok || found && value != shared {
+			return "", false
+		}
+		shared, found = value, true
+	}
+	return shared, found
+}
+
+// FormatDuration shows a duration as m:ss, or h:mm:ss if it's at least an hour long
+func FormatDuration(d time.Duration) string {
+	seconds := int(d / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// FormatEntry fills in the template for the entry at index, falling back to the entry's name if the template is empty or a field is missing
+func FormatEntry(t Template, arr musicarray.MusicArray, index int) string {
+	if t.IsEmpty() {
+		return arr[index].Name
+	}
+	if s, ok := t.Execute(EntryLookup(arr, index)); ok {
+		return s
+	}
+	return arr[index].Name
+}
@@ -0,0 +1,150 @@
+// Package titleformat evaluates templates such as "%tracknumber%. %title% [(%duration%)]" against entries
+//
+// %field% is replaced by the value of the field, which is anything usable in a query such as a tag name
+// %a|b% uses the first of the fields a and b that exists
+// text between [ and ] is left out if any field inside of it is missing, and brackets can be nested
+// %% is a literal %, and a backslash makes the character after it literal, so \[ \] and \\ are a literal [, ] and \
+// outside of brackets a missing field makes the whole template fail, so the caller can fall back to the entry's name
+package titleformat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Lookup returns the value of a field and whether it exists
+type Lookup func(field string) (string, bool)
+
+type Template struct {
+	source string
+	parts  []part
+}
+
+// part is literal text, a field or an optional section
+type part struct {
+	text     string
+	fields   []string
+	optional []part
+}
+
+func Parse(s string) (Template, error) {
+	parts, rest, err := parseParts(s, false)
+	if err != nil {
+		return Template{}, err
+	}
+	if rest != "" {
+		return Template{}, errors.New(fmt.Sprintf("titleformat: unmatched ']' in '%s'.", s))
+	}
+	return Template{source: s, parts: parts}, nil
+}
+
+// MustParse is Parse for templates known to be valid
+func MustParse(s string) Template {
+	t, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// parseParts reads parts until the end of s or, inside of brackets, the closing bracket, returning what is left after it
+func parseParts(s string, inBrackets bool) ([]part, string, error) {
+	parts := []part{}
+	literal := strings.Builder{}
+	flush := func() {
+		if literal.Len() > 0 {
+			parts = append(parts, part{text: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, "%%"):
+			literal.WriteByte('%')
+			s = s[2:]
+		case s[0] == '\\':
+			if len(s) == 1 {
+				return nil, "", errors.New("titleformat: a '\\' is missing the character it escapes.")
+			}
+			literal.WriteByte(s[1])
+			s = s[2:]
+		case s[0] == '%':
+			end := strings.IndexByte(s[1:], '%')
+			if end == -1 {
+				return nil, "", errors.New("titleformat: a field is missing its closing '%'.")
+			}
+			flush()
+			parts = append(parts, part{fields: strings.Split(strings.ToLower(s[1:end+1]), "|")})
+			s = s[end+2:]
+		case s[0] == '[':
+			flush()
+			optional, rest, err := parseParts(s[1:], true)
+			if err != nil {
+				return nil, "", err
+			}
+			parts = append(parts, part{optional: optional})
+			s = rest
+		case s[0] == ']':
+			if !inBrackets {
+				return parts, s, nil
+			}
+			flush()
+			return parts, s[1:], nil
+		default:
+			literal.WriteByte(s[0])
+			s = s[1:]
+		}
+	}
+	if inBrackets {
+		return nil, "", errors.New("titleformat: a '[' is missing its closing ']'.")
+	}
+	flush()
+	return parts, "", nil
+}
+
+// Execute fills in the template, returning false if a field outside of brackets is missing
+func (t Template) Execute(lookup Lookup) (string, bool) {
+	return execute(t.parts, lookup)
+}
+
+func execute(parts []part, lookup Lookup) (string, bool) {
+	result := strings.Builder{}
+	for _, p := range parts {
+		switch {
+		case p.fields != nil:
+			value, ok := firstField(p.fields, lookup)
+			if !ok {
+				return "", false
+			}
+			result.WriteString(value)
+		case p.optional != nil:
+			if value, ok := execute(p.optional, lookup); ok {
+				result.WriteString(value)
+			}
+		default:
+			result.WriteString(p.text)
+		}
+	}
+	return result.String(), true
+}
+
+func firstField(fields []string, lookup Lookup) (string, bool) {
+	for _, field := range fields {
+		if value, ok := lookup(field); ok && value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// IsEmpty reports whether the template has no parts, which is used to mean entries are shown by name
+func (t Template) IsEmpty() bool {
+	return len(t.parts) == 0
+}
+
+// String returns the template as it was written
+func (t Template) String() string {
+	return t.source
+}
@@ -0,0 +1,79 @@
+package titleformat
+
+import (
+	"testing"
+)
+
+func TestExecute(t *testing.T) {
+	fields := map[string]string{
+		"title":       "Roygbiv",
+		"artist":      "Boards of Canada",
+		"tracknumber": "4",
+		"empty":       "",
+	}
+	lookup := func(field string) (string, bool) {
+		value, ok := fields[field]
+		return value, ok
+	}
+
+	tests := []struct {
+		template string
+		want     string
+		ok       bool
+	}{
+		{"%title%", "Roygbiv", true},
+		{"%tracknumber%. %title%", "4. Roygbiv", true},
+		{"%TITLE%", "Roygbiv", true},
+		{"%album|title%", "Roygbiv", true},
+		{"%empty|artist%", "Boards of Canada", true},
+		{"%album%", "", false},
+		{"%empty%", "", false},
+		{"%title%[ (%album%)]", "Roygbiv", true},
+		{"[%tracknumber%. ]%title%", "4. Roygbiv", true},
+		{"[no fields]", "no fields", true},
+		// a missing field only drops the innermost section it is in
+		{"[%artist%[ - %album%]]: %title%", "Boards of Canada: Roygbiv", true},
+		{"[%artist%[ - %tracknumber%]]", "Boards of Canada - 4", true},
+		{"[%album%[ - %tracknumber%]]%title%", "Roygbiv", true},
+		{"[[%album%]]%title%", "Roygbiv", true},
+		{"100%% %title%", "100% Roygbiv", true},
+		{`\[%tracknumber%\] %title%`, "[4] Roygbiv", true},
+		{`\\%title%`, `\Roygbiv`, true},
+		{`[\[%album%\]]%title%`, "Roygbiv", true},
+		{"", "", true},
+	}
+
+	for _, test := range tests {
+		template, err := Parse(test.template)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.template, err)
+			continue
+		}
+		got, ok := template.Execute(lookup)
+		if got != test.want || ok != test.ok {
+			t.Errorf("Execute(%q) = %q, %v; want %q, %v", test.template, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, template := range []string{
+		"%title",
+		"[%title%",
+		"%title%]",
+		"[[%title%]",
+		"[%title%]]",
+		`%title%\`,
+	} {
+		if _, err := Parse(template); err == nil {
+			t.Errorf("Parse(%q) succeeded, expected an error", template)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	source := `[%tracknumber%. ]%title% \[%%\]`
+	if got := MustParse(source).String(); got != source {
+		t.Errorf("String() = %q, want %q", got, source)
+	}
+}
@@ -1,23 +1,143 @@
 package windowwriter
 
 import (
+	"github.com/StructsNotClasses/mim/textwidth"
+
 	gnc "github.com/rthornton128/goncurses"
+
+	"strings"
+	"sync"
 )
 
+// tabWidth is how far apart ncurses places tab stops
+const tabWidth = 8
+
+// historyLines is how many lines of output are kept so they can be printed again after the window is resized
+const historyLines = 500
+
+// segment is a piece of a line written with the same attributes
+type segment struct {
+	text       string
+	attributes gnc.Char
+}
+
 type WindowWriter struct {
-	win *gnc.Window
+	win     *gnc.Window
+	lock    sync.Mutex
+	history [][]segment
+	// hidden windows only record what is written until they are shown again
+	hidden bool
 }
 
-func New(win *gnc.Window) WindowWriter {
-    return WindowWriter{win}
+func New(win *gnc.Window) *WindowWriter {
+	return &WindowWriter{
+		win:     win,
+		history: [][]segment{{}},
+	}
 }
 
-func (w WindowWriter) Write(bs []byte) (n int, err error) {
-    w.win.Print(string(bs))
-    w.win.Refresh()
+func (w *WindowWriter) Write(bs []byte) (n int, err error) {
+	w.WriteString(string(bs))
 	return len(bs), nil
 }
 
-func (w WindowWriter) Close() error {
+func (w *WindowWriter) WriteString(s string) {
+	w.WriteStyled(s, gnc.A_NORMAL)
+}
+
+// WriteStyled writes s with the given attributes, which are kept when the window is redrawn
+func (w *WindowWriter) WriteStyled(s string, attributes gnc.Char) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.record(s, attributes)
+	if !w.hidden {
+		w.print(s, attributes)
+		w.win.Refresh()
+	}
+}
+
+func (w *WindowWriter) SetHidden(hidden bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.hidden = hidden
+}
+
+// print writes s at the cursor, wrapping lines by display width itself
+// a wide character that doesn't fit at the end of a row starts the next one instead of being split across them
+func (w *WindowWriter) print(s string, attributes gnc.Char) {
+	if attributes != gnc.A_NORMAL {
+		w.win.AttrOn(attributes)
+		defer w.win.AttrOff(attributes)
+	}
+	_, width := w.win.MaxYX()
+	_, x := w.win.CursorYX()
+	start := 0
+	for i, r := range s {
+		switch r {
+		case '\n':
+			x = 0
+			continue
+		case '\t':
+			x = (x/tabWidth + 1) * tabWidth
+		default:
+			columns := textwidth.Rune(r)
+			if x+columns > width && x > 0 {
+				w.win.Print(s[start:i] + "\n")
+				start = i
+				x = 0
+			}
+			x += columns
+		}
+		// ncurses moves to the next row by itself once the last column is written
+		if x >= width {
+			x = 0
+		}
+	}
+	w.win.Print(s[start:])
+}
+
+// record appends s to the history, where the last line is the one still being written
+func (w *WindowWriter) record(s string, attributes gnc.Char) {
+	for n, text := range strings.Split(s, "\n") {
+		if n > 0 {
+			w.history = append(w.history, []segment{})
+		}
+		if text != "" {
+			last := len(w.history) - 1
+			w.history[last] = append(w.history[last], segment{text, attributes})
+		}
+	}
+	if len(w.history) > historyLines {
+		w.history = w.history[len(w.history)-historyLines:]
+	}
+}
+
+// Redraw clears the window and prints the history again, which wraps it to the current width of the window
+func (w *WindowWriter) Redraw() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.hidden {
+		return
+	}
+	w.win.Erase()
+	for n, line := range w.history {
+		if n > 0 {
+			w.win.Print("\n")
+		}
+		for _, seg := range line {
+			w.print(seg.text, seg.attributes)
+		}
+	}
+	w.win.Refresh()
+}
+
+func (w *WindowWriter) Window() *gnc.Window {
+	return w.win
+}
+
+func (w *WindowWriter) Close() error {
 	return nil
 }